@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/JustForWorld/banner-shift/internal/config"
+)
+
+// runConfig prints effective config after files and env are applied, secrets are redacted
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: banner-shift config print [--config file]...")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("banner-shift config print", flag.ExitOnError)
+	files := config.Files{}
+	fs.Var(&files, "config", "config file, repeat to layer files, later files override earlier ones")
+	_ = fs.Parse(args[1:])
+
+	cfg := config.MustLoadFiles(files)
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	return 0
+}
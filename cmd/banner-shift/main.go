@@ -2,18 +2,44 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/JustForWorld/banner-shift/internal/cache/warmup"
 	"github.com/JustForWorld/banner-shift/internal/config"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/bulk"
 	delete_banner "github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/delete"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/export"
 	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/get"
+	getbatch "github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/get-batch"
+	getbyid "github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/get-by-id"
 	getlist "github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/get-list"
+	importer "github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/import"
 	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/save"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/search"
 	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/update"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/cache/flush"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/cache/warm"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/debug/vars"
+	contentschema "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/content-schema"
+	defaultbanner "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/default-banner"
+	delete_feature "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/delete"
+	get_feature "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/get"
+	getlist_feature "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/get-list"
+	save_feature "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/save"
+	update_feature "github.com/JustForWorld/banner-shift/internal/http-server/handlers/feature/update"
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/health"
+	delete_tag "github.com/JustForWorld/banner-shift/internal/http-server/handlers/tag/delete"
+	get_tag "github.com/JustForWorld/banner-shift/internal/http-server/handlers/tag/get"
+	getlist_tag "github.com/JustForWorld/banner-shift/internal/http-server/handlers/tag/get-list"
+	save_tag "github.com/JustForWorld/banner-shift/internal/http-server/handlers/tag/save"
+	update_tag "github.com/JustForWorld/banner-shift/internal/http-server/handlers/tag/update"
+	"github.com/JustForWorld/banner-shift/internal/http-server/ratelimit"
+	"github.com/JustForWorld/banner-shift/internal/schema"
 	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
 	"github.com/JustForWorld/banner-shift/internal/storage/redis"
 	"github.com/go-chi/chi/middleware"
@@ -29,50 +55,112 @@ const (
 
 var (
 	tokenAuth *jwtauth.JWTAuth
+	// logLevel is changed on config reload
+	logLevel = new(slog.LevelVar)
 )
 
-func init() {
-	tokenAuth = jwtauth.New("HS256", []byte(fmt.Sprint(time.Now().Unix())), nil)
-}
-
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "seed":
+			os.Exit(runSeed(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
 	cfg, usr := config.MustLoad()
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	log := setupLogger(cfg.Env)
+	log := setupLogger(cfg.Env, cfg.LogLevel)
 	log.Info("starting banner-shift", slog.String("env", cfg.Env))
 	log.Debug("debug messages are enabled")
 
+	secret := cfg.HTTPServer.JWTSecret
+	if secret == "" {
+		secret = fmt.Sprint(time.Now().Unix())
+	}
+	tokenAuth = jwtauth.New("HS256", []byte(secret), nil)
+
 	_, tokenString, _ := tokenAuth.Encode(map[string]interface{}{"username": usr.Username, "role": usr.Role, "tag": usr.Tag})
 	log.Debug("current jwt token", slog.String("jwt", tokenString))
 
-	redis, err := redis.New(
-		ctx,
-		cfg.Redis.Addr,
-		cfg.Redis.User,
-		cfg.Redis.Password,
-		cfg.Redis.DB,
-		cfg.Redis.Protocol,
-	)
+	redis, err := redis.New(ctx, redis.Options{
+		Mode:             cfg.Redis.Mode,
+		Addrs:            cfg.Redis.Addresses(),
+		MasterName:       cfg.Redis.MasterName,
+		User:             cfg.Redis.User,
+		Password:         cfg.Redis.Password,
+		SentinelUser:     cfg.Redis.SentinelUser,
+		SentinelPassword: cfg.Redis.SentinelPassword,
+		DB:               cfg.Redis.DB,
+		Protocol:         cfg.Redis.Protocol,
+		PoolSize:         cfg.Redis.Pool.Size,
+		MinIdleConns:     cfg.Redis.Pool.MinIdleConns,
+		DialTimeout:      cfg.Redis.Pool.DialTimeout,
+		ReadTimeout:      cfg.Redis.Pool.ReadTimeout,
+		WriteTimeout:     cfg.Redis.Pool.WriteTimeout,
+		TLS: redis.TLSOptions{
+			Enabled:            cfg.Redis.TLS.Enabled,
+			CAFile:             cfg.Redis.TLS.CAFile,
+			CertFile:           cfg.Redis.TLS.CertFile,
+			KeyFile:            cfg.Redis.TLS.KeyFile,
+			ServerName:         cfg.Redis.TLS.ServerName,
+			InsecureSkipVerify: cfg.Redis.TLS.InsecureSkipVerify,
+		},
+		KeyPrefix:        cfg.Redis.KeyPrefix,
+		BannerTTL:        cfg.Redis.Cache.BannerTTL,
+		NotFoundTTL:      cfg.Redis.Cache.NotFoundTTL,
+		KeyVersion:       cfg.Redis.Cache.KeyVersion,
+		FailureThreshold: cfg.Redis.Breaker.FailureThreshold,
+		OpenTimeout:      cfg.Redis.Breaker.OpenTimeout,
+	})
 	if err != nil {
-		slog.Error("failed to init Redis storage: %w", err)
+		log.Error("failed to init Redis storage", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	if redis.Degraded() {
+		log.Warn("Redis is unavailable, serving from PostgreSQL only")
+	}
+	go redis.Probe(context.Background(), cfg.Redis.Breaker.ProbeInterval)
 
-	storage, err := postgresql.New(
-		ctx,
-		cfg.PostgreSQL.User,
-		cfg.PostgreSQL.Password,
-		cfg.PostgreSQL.Host,
-		cfg.PostgreSQL.DB,
-		cfg.PostgreSQL.Port,
-	)
+	expvar.Publish("redis_degraded", expvar.Func(func() any {
+		return redis.Degraded()
+	}))
+
+	storage, err := newStorage(ctx, cfg)
 	if err != nil {
-		slog.Error("failed to init PostgreSQL storage: %w", err)
+		log.Error("failed to init PostgreSQL storage", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	warmer := warmup.New(log, storage, redis, cfg.Redis.Warmup.BatchSize)
+	if cfg.Redis.Warmup.OnStartup && !redis.Degraded() {
+		// warm-up failure is not fatal, banners are read through on demand
+		_ = warmer.Run(context.Background())
+	}
+
+	// in redis mode limits are shared by replicas, single node buckets are used while Redis is down
+	var tokens ratelimit.TokenTaker = ratelimit.NewMemory()
+	if cfg.RateLimit.Mode == "redis" {
+		tokens = redis
+	}
+	limiter := ratelimit.New(log, tokens)
+	setRateLimits(limiter, cfg)
+
+	// reloadable settings are applied to running components on SIGHUP or change of config files
+	reloader := config.NewReloader(log, cfg)
+	reloader.OnReload(func(cfg *config.Config) {
+		setLogLevel(cfg.Env, cfg.LogLevel)
+		redis.SetTTL(cfg.Redis.Cache.BannerTTL, cfg.Redis.Cache.NotFoundTTL)
+		setRateLimits(limiter, cfg)
+	})
+	go reloader.Run(context.Background())
+
+	// banner content is checked against JSON Schema of its feature
+	validator := schema.NewValidator(storage)
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
@@ -80,15 +168,59 @@ func main() {
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
 
-	router.Use(jwtauth.Verifier(tokenAuth))
-	router.Use(jwtauth.Authenticator(tokenAuth))
+	router.Get("/health", health.New(log, storage, redis))
+
+	router.Group(func(router chi.Router) {
+		router.Use(jwtauth.Verifier(tokenAuth))
+
+		// limits are checked before authentication, so requests without valid token are limited by IP
+		router.Group(func(router chi.Router) {
+			router.Use(limiter.Limit(ratelimit.ScopeUserBanner))
+			router.Use(jwtauth.Authenticator(tokenAuth))
+
+			router.Get("/user_banner", get.New(log, storage, redis))
+			router.Get("/user_banner/batch", getbatch.New(log, storage, redis))
+			router.Post("/user_banner/batch", getbatch.New(log, storage, redis))
+		})
+
+		router.Group(func(router chi.Router) {
+			router.Use(limiter.Limit(ratelimit.ScopeAdmin))
+			router.Use(jwtauth.Authenticator(tokenAuth))
 
-	router.Get("/banner", getlist.New(log, storage))
-	router.Get("/user_banner", get.New(log, storage, redis))
+			router.Get("/banner", getlist.New(log, storage, cfg.Pagination.DefaultLimit, cfg.Pagination.MaxLimit))
+			router.Get("/banner/search", search.New(log, storage, cfg.Pagination.DefaultLimit, cfg.Pagination.MaxLimit))
+			router.Post("/banner", save.New(log, storage, redis, validator, storage, cfg.HTTPServer.IdempotencyTTL))
+			router.Post("/banner/bulk", bulk.New(log, storage, validator))
+			router.Get("/banner/export", export.New(log, storage))
+			router.Post("/banner/import", importer.New(log, storage, validator))
+			router.Get("/banner/{id}", getbyid.New(log, storage))
+			router.Patch("/banner/{id}", update.New(log, storage, validator, redis))
+			router.Delete("/banner/{id}", delete_banner.New(log, storage, redis))
 
-	router.Post("/banner", save.New(log, storage, redis))
-	router.Patch("/banner/{id}", update.New(log, storage))
-	router.Delete("/banner/{id}", delete_banner.New(log, storage))
+			router.Get("/feature", getlist_feature.New(log, storage))
+			router.Get("/feature/{id}", get_feature.New(log, storage))
+			router.Post("/feature", save_feature.New(log, storage))
+			router.Patch("/feature/{id}", update_feature.New(log, storage))
+			router.Delete("/feature/{id}", delete_feature.New(log, storage))
+			router.Put("/feature/{id}/default_banner", defaultbanner.New(log, storage, redis))
+			router.Delete("/feature/{id}/default_banner", defaultbanner.Delete(log, storage, redis))
+			router.Put("/feature/{id}/content_schema", contentschema.New(log, storage))
+			router.Delete("/feature/{id}/content_schema", contentschema.Delete(log, storage))
+			router.Post("/feature/{id}/content_schema/validate", contentschema.Validate(log, storage))
+
+			router.Get("/tag", getlist_tag.New(log, storage))
+			router.Get("/tag/{id}", get_tag.New(log, storage))
+			router.Post("/tag", save_tag.New(log, storage))
+			router.Patch("/tag/{id}", update_tag.New(log, storage))
+			router.Delete("/tag/{id}", delete_tag.New(log, storage))
+
+			router.Post("/cache/warm", warm.New(log, warmer))
+			router.Get("/cache/warm", warm.Progress(warmer))
+			router.Post("/cache/flush", flush.New(log, redis))
+
+			router.Get("/debug/vars", vars.New(log))
+		})
+	})
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 	server := &http.Server{
@@ -105,29 +237,69 @@ func main() {
 	log.Info("server stopped")
 }
 
-func setupLogger(env string) *slog.Logger {
+func newStorage(ctx context.Context, cfg *config.Config) (*postgresql.Storage, error) {
+	return postgresql.New(ctx, postgresql.Options{
+		Host:             cfg.PostgreSQL.Host,
+		Port:             cfg.PostgreSQL.Port,
+		User:             cfg.PostgreSQL.User,
+		Password:         cfg.PostgreSQL.Password,
+		DB:               cfg.PostgreSQL.DB,
+		SSLMode:          cfg.PostgreSQL.SSLMode,
+		SSLRootCert:      cfg.PostgreSQL.SSLRootCert,
+		SSLCert:          cfg.PostgreSQL.SSLCert,
+		SSLKey:           cfg.PostgreSQL.SSLKey,
+		StatementTimeout: cfg.PostgreSQL.StatementTimeout,
+		MaxOpenConns:     cfg.PostgreSQL.MaxOpenConns,
+		MaxIdleConns:     cfg.PostgreSQL.MaxIdleConns,
+		ConnMaxLifetime:  cfg.PostgreSQL.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.PostgreSQL.ConnMaxIdleTime,
+		ReplicaDSN:       cfg.PostgreSQL.ReplicaDSN,
+		SearchFields:     cfg.PostgreSQL.Search.Fields,
+		SearchLanguage:   cfg.PostgreSQL.Search.Language,
+	})
+}
+
+func setRateLimits(limiter *ratelimit.Limiter, cfg *config.Config) {
+	limiter.Set(cfg.RateLimit.Enabled, map[string]ratelimit.Limit{
+		ratelimit.ScopeUserBanner: {Rate: cfg.RateLimit.UserBanner.Rate, Burst: cfg.RateLimit.UserBanner.Burst},
+		ratelimit.ScopeAdmin:      {Rate: cfg.RateLimit.Admin.Rate, Burst: cfg.RateLimit.Admin.Burst},
+	})
+}
+
+func setupLogger(env, level string) *slog.Logger {
 	var log *slog.Logger
 
+	setLogLevel(env, level)
+
 	switch env {
 	case envLocal:
 		log = slog.New(slog.NewTextHandler(os.Stdout,
 			&slog.HandlerOptions{
-				Level: slog.LevelDebug,
+				Level: logLevel,
 			},
 		))
-	case envDev:
+	case envDev, envProd:
 		log = slog.New(slog.NewJSONHandler(os.Stdout,
 			&slog.HandlerOptions{
-				Level: slog.LevelDebug,
-			},
-		))
-	case envProd:
-		log = slog.New(slog.NewJSONHandler(os.Stdout,
-			&slog.HandlerOptions{
-				Level: slog.LevelInfo,
+				Level: logLevel,
 			},
 		))
 	}
 
 	return log
 }
+
+// setLogLevel applies log_level, empty level is debug except for prod
+func setLogLevel(env, level string) {
+	if level == "" {
+		level = "debug"
+		if env == envProd {
+			level = "info"
+		}
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err == nil {
+		logLevel.Set(l)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/config"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/seed"
+)
+
+// runSeed fills storage with features, tags and sample banners, it replaces
+// scripts/init.sql and can be run again safely
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("banner-shift seed", flag.ExitOnError)
+	files := config.Files{}
+	fs.Var(&files, "config", "config file, repeat to layer files, later files override earlier ones")
+	fixturePath := fs.String("fixture", "", "YAML fixture of features, tags and banners, counts are ignored with it")
+	features := fs.Int("features", 10, "number of features feature-1..N")
+	tags := fs.Int("tags", 10, "number of tags tag-1..N")
+	banners := fs.Int("banners", 0, "number of sample banners")
+	_ = fs.Parse(args)
+
+	cfg := config.MustLoadFiles(files)
+	log := setupLogger(cfg.Env, cfg.LogLevel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var (
+		fixture *seed.Fixture
+		err     error
+	)
+	if *fixturePath != "" {
+		fixture, err = seed.LoadFixture(*fixturePath)
+	} else {
+		fixture, err = seed.Generate(*features, *tags, *banners)
+	}
+	if err != nil {
+		log.Error("invalid fixture", slog.String("error", err.Error()))
+		return 1
+	}
+
+	storage, err := newStorage(ctx, cfg)
+	if err != nil {
+		log.Error("failed to init PostgreSQL storage", slog.String("error", err.Error()))
+		return 1
+	}
+
+	report, err := seed.New(log, storage, schema.NewValidator(storage)).Run(ctx, fixture)
+	if err != nil {
+		log.Error("failed to seed storage", slog.String("error", err.Error()))
+		return 1
+	}
+
+	fmt.Fprintf(os.Stdout, "features: %d created, %d existed\ntags: %d created, %d existed\nbanners: %d created, %d existed\n",
+		report.FeaturesCreated, report.FeaturesExisted,
+		report.TagsCreated, report.TagsExisted,
+		report.BannersCreated, report.BannersExisted)
+
+	return 0
+}
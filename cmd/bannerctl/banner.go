@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/JustForWorld/banner-shift/internal/client"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+)
+
+var bannerHeader = []string{"ID", "FEATURE", "TAGS", "ACTIVE", "VERSION", "UPDATED", "CONTENT"}
+
+func runBanner(ctx context.Context, e *env, args []string) error {
+	name, args, err := subcommand("banner", []string{"list", "get", "create", "update", "delete"}, args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return bannerList(ctx, e, args)
+	case "get":
+		return bannerGet(ctx, e, args)
+	case "create":
+		return bannerCreate(ctx, e, args)
+	case "update":
+		return bannerUpdate(ctx, e, args)
+	default:
+		return bannerDelete(ctx, e, args)
+	}
+}
+
+func bannerList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("banner list")
+	query := url.Values{}
+	for _, name := range []string{"feature_id", "tag_id", "is_active", "limit", "offset", "cursor", "sort_by", "order",
+		"created_from", "created_to", "updated_from", "updated_to"} {
+		name := name
+		fs.Func(strings.ReplaceAll(name, "_", "-"), "query parameter "+name+" of GET /banner", func(value string) error {
+			query.Add(name, value)
+			return nil
+		})
+	}
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	page, err := e.client.ListBanners(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(page.Banners))
+	for i, banner := range page.Banners {
+		rows[i] = bannerRow(banner)
+	}
+	if err := e.out.print(page, bannerHeader, rows); err != nil {
+		return err
+	}
+	if e.out.format == outputTable {
+		fmt.Fprintf(e.out.w, "\ntotal: %d", page.Total)
+		if page.NextCursor != "" {
+			fmt.Fprintf(e.out.w, ", next: --cursor %s", page.NextCursor)
+		}
+		fmt.Fprintln(e.out.w)
+	}
+
+	return nil
+}
+
+func bannerGet(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("banner get <id>")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	banner, err := e.client.GetBanner(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return e.out.print(banner, bannerHeader, [][]string{bannerRow(banner)})
+}
+
+func bannerCreate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("banner create")
+	input := bannerFlags(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if input.IsActive == nil {
+		input.IsActive = new(bool)
+	}
+
+	id, err := e.client.CreateBanner(ctx, *input)
+	if err != nil {
+		return err
+	}
+
+	return e.out.message(map[string]int64{"banner_id": id}, "created banner %d", id)
+}
+
+func bannerUpdate(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("banner update <id>")
+	version := fs.Int64("if-match", 0, "update only if banner still has this version")
+	input := bannerFlags(fs)
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := e.client.UpdateBanner(ctx, id, *input, *version); err != nil {
+		return err
+	}
+
+	return e.out.message(map[string]int64{"banner_id": id}, "updated banner %d", id)
+}
+
+func bannerDelete(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("banner delete <id>")
+	version := fs.Int64("if-match", 0, "delete only if banner still has this version")
+	id, err := parseID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if err := e.client.DeleteBanner(ctx, id, *version); err != nil {
+		return err
+	}
+
+	return e.out.message(map[string]int64{"banner_id": id}, "deleted banner %d", id)
+}
+
+// bannerFlags defines flags of banner fields, flags that are not set stay empty
+func bannerFlags(fs *flag.FlagSet) *client.BannerInput {
+	input := &client.BannerInput{}
+
+	fs.Int64Var(&input.FeatureID, "feature-id", 0, "feature of banner")
+	fs.Func("tag-ids", "comma separated tags of banner", func(value string) (err error) {
+		input.TagIDs, err = parseIDs(value)
+		return err
+	})
+	fs.Func("content", "content JSON, @file to read it from file or @- from stdin", func(value string) (err error) {
+		input.Content, err = readContent(value)
+		return err
+	})
+	fs.Func("active", "true or false", func(value string) error {
+		isActive, err := strconv.ParseBool(value)
+		input.IsActive = &isActive
+		return err
+	})
+
+	return input
+}
+
+// parseID parses flags and the only argument id
+func parseID(fs *flag.FlagSet, args []string) (int64, error) {
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return 0, err
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n", fs.Name())
+		return 0, errUsage
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("id %q is not integer", args[0])
+	}
+
+	return id, nil
+}
+
+func parseIDs(value string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("id %q is not integer", part)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func readContent(value string) (json.RawMessage, error) {
+	data := []byte(value)
+	if strings.HasPrefix(value, "@") {
+		var err error
+		if value == "@-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(value[1:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("content is not valid JSON")
+	}
+
+	return data, nil
+}
+
+func bannerRow(banner *postgresql.Banner) []string {
+	return []string{
+		fmt.Sprint(banner.BannerID),
+		fmt.Sprint(banner.FeatureID),
+		ids(banner.TagIDs),
+		fmt.Sprint(banner.IsActive),
+		fmt.Sprint(banner.Version),
+		banner.UpdatedAT,
+		truncate(string(banner.Content), 60),
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a set of profiles, one per environment, e.g.
+//
+//	current: local
+//	profiles:
+//	  local:
+//	    url: http://localhost:8080
+//	    jwt_secret: local-secret
+//	  prod:
+//	    url: https://banner.example.com
+//	    token: eyJhbGciOi...
+type Config struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+type Profile struct {
+	URL     string        `yaml:"url"`
+	Token   string        `yaml:"token"`
+	Timeout time.Duration `yaml:"timeout"`
+	// Output is table or json
+	Output string `yaml:"output"`
+	// JWTSecret is http_server.jwt_secret of server, with it and empty Token
+	// a token is minted from Username, Role and Tag, for local development only
+	JWTSecret string        `yaml:"jwt_secret"`
+	Username  string        `yaml:"username"`
+	Role      string        `yaml:"role"`
+	Tag       int64         `yaml:"tag"`
+	TokenTTL  time.Duration `yaml:"token_ttl"`
+}
+
+// defaultProfile is used when config file does not exist
+var defaultProfile = Profile{
+	URL:       "http://localhost:8080",
+	JWTSecret: "local-secret",
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("BANNERCTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bannerctl.yaml"
+	}
+
+	return filepath.Join(dir, "bannerctl", "config.yaml")
+}
+
+// LoadConfig reads profiles, missing file gives single profile local
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		cfg.Current = "local"
+		cfg.Profiles = map[string]Profile{"local": defaultProfile}
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("cannot read config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// profiles hold tokens and secrets
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Profile returns profile by name, empty name is current profile; defaults are filled
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" && len(c.Profiles) == 1 {
+		for only := range c.Profiles {
+			name = only
+		}
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return profile, fmt.Errorf("profile %q is not found, known profiles: %v", name, c.Names())
+	}
+
+	if profile.URL == "" {
+		profile.URL = defaultProfile.URL
+	}
+	if profile.Timeout == 0 {
+		profile.Timeout = 30 * time.Second
+	}
+	if profile.Output == "" {
+		profile.Output = outputTable
+	}
+	if profile.Username == "" {
+		profile.Username = "bannerctl"
+	}
+	if profile.Role == "" {
+		profile.Role = "admin"
+	}
+	if profile.TokenTTL == 0 {
+		profile.TokenTTL = time.Hour
+	}
+
+	return profile, nil
+}
+
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Mint signs token with claims the server reads, ttl 0 gives token without expiration
+func (p Profile) Mint(username, role string, tag int64, ttl time.Duration) (string, error) {
+	if p.JWTSecret == "" {
+		return "", fmt.Errorf("profile has no jwt_secret to mint token")
+	}
+
+	claims := map[string]interface{}{"username": username, "role": role, "tag": tag}
+	if ttl > 0 {
+		jwtauth.SetIssuedNow(claims)
+		jwtauth.SetExpiryIn(claims, ttl)
+	}
+
+	_, token, err := jwtauth.New("HS256", []byte(p.JWTSecret), nil).Encode(claims)
+
+	return token, err
+}
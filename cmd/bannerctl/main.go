@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/JustForWorld/banner-shift/internal/client"
+)
+
+const usage = `bannerctl manages banner-shift through its HTTP API
+
+Usage:
+  bannerctl [--config path] [--profile name] [--output table|json] <command> [flags] [args]
+
+Commands:
+  banner list|get|create|update|delete
+  feature list|get|create|update|delete
+  tag list|get|create|update|delete
+  export [--format jsonl|csv|yaml] [--out file]
+  import [--format jsonl|csv|yaml] [--on-conflict skip|overwrite|fail] [--dry-run] file
+  token [--username name] [--role admin|user] [--tag id] [--ttl duration]
+  profile list|use <name>
+
+Run "bannerctl <command> --help" for flags of command.
+`
+
+// errUsage is returned for wrong arguments, usage is already printed
+var errUsage = errors.New("invalid arguments")
+
+// env is what commands share: selected profile, API client and output
+type env struct {
+	config  *Config
+	profile Profile
+	client  *client.Client
+	out     *output
+}
+
+func main() {
+	global := flag.NewFlagSet("bannerctl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := global.String("config", defaultConfigPath(), "path of profiles config")
+	profileName := global.String("profile", os.Getenv("BANNERCTL_PROFILE"), "profile of environment, default is current profile of config")
+	format := global.String("output", "", "output format: table or json, default is output of profile")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, *configPath, *profileName, *format, global.Args()); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, configPath, profileName, format string, args []string) error {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	// profiles are managed without selecting one
+	if args[0] == "profile" {
+		return runProfile(cfg, configPath, args[1:])
+	}
+
+	profile, err := cfg.Profile(profileName)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		format = profile.Output
+	}
+	out, err := newOutput(os.Stdout, format)
+	if err != nil {
+		return err
+	}
+
+	token := profile.Token
+	if token == "" && profile.JWTSecret != "" {
+		// local development: act as admin of profile
+		if token, err = profile.Mint(profile.Username, profile.Role, profile.Tag, profile.TokenTTL); err != nil {
+			return err
+		}
+	}
+
+	e := &env{
+		config:  cfg,
+		profile: profile,
+		client:  client.New(profile.URL, token, profile.Timeout),
+		out:     out,
+	}
+
+	switch args[0] {
+	case "banner":
+		return runBanner(ctx, e, args[1:])
+	case "feature":
+		return runMeta(ctx, e, featureResource, args[1:])
+	case "tag":
+		return runMeta(ctx, e, tagResource, args[1:])
+	case "export":
+		return runExport(ctx, e, args[1:])
+	case "import":
+		return runImport(ctx, e, args[1:])
+	case "token":
+		return runToken(e, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return errUsage
+	}
+}
+
+// subcommand returns name of subcommand of group and its arguments
+func subcommand(group string, names []string, args []string) (string, []string, error) {
+	if len(args) > 0 {
+		for _, name := range names {
+			if args[0] == name {
+				return name, args[1:], nil
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Usage: bannerctl %s %s\n", group, strings.Join(names, "|"))
+	return "", nil, errUsage
+}
+
+// newFlagSet returns flags of command, parse errors are reported as errUsage
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("bannerctl "+name, flag.ContinueOnError)
+}
+
+// parseFlags parses flags placed before and after arguments and returns arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JustForWorld/banner-shift/internal/client"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+)
+
+// resource is feature or tag, both have the same meta fields and commands
+type resource struct {
+	name   string
+	header []string
+	list   func(ctx context.Context, c *client.Client, query url.Values) (interface{}, [][]string, error)
+	get    func(ctx context.Context, c *client.Client, id int64) (interface{}, []string, error)
+	create func(c *client.Client, ctx context.Context, meta client.MetaInput) (int64, error)
+	update func(c *client.Client, ctx context.Context, id int64, meta client.MetaInput, force bool) error
+	delete func(c *client.Client, ctx context.Context, id int64, force bool) error
+}
+
+var featureResource = resource{
+	name:   "feature",
+	header: []string{"ID", "NAME", "OWNER", "ARCHIVED", "DEFAULT BANNER", "DESCRIPTION"},
+	list: func(ctx context.Context, c *client.Client, query url.Values) (interface{}, [][]string, error) {
+		features, err := c.ListFeatures(ctx, query)
+		rows := make([][]string, len(features))
+		for i, feature := range features {
+			rows[i] = featureRow(feature)
+		}
+		return features, rows, err
+	},
+	get: func(ctx context.Context, c *client.Client, id int64) (interface{}, []string, error) {
+		feature, err := c.GetFeature(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return feature, featureRow(feature), nil
+	},
+	create: (*client.Client).CreateFeature,
+	update: (*client.Client).UpdateFeature,
+	delete: (*client.Client).DeleteFeature,
+}
+
+var tagResource = resource{
+	name:   "tag",
+	header: []string{"ID", "NAME", "OWNER", "ARCHIVED", "PARENT", "DESCRIPTION"},
+	list: func(ctx context.Context, c *client.Client, query url.Values) (interface{}, [][]string, error) {
+		tags, err := c.ListTags(ctx, query)
+		rows := make([][]string, len(tags))
+		for i, tag := range tags {
+			rows[i] = tagRow(tag)
+		}
+		return tags, rows, err
+	},
+	get: func(ctx context.Context, c *client.Client, id int64) (interface{}, []string, error) {
+		tag, err := c.GetTag(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tag, tagRow(tag), nil
+	},
+	create: (*client.Client).CreateTag,
+	update: (*client.Client).UpdateTag,
+	delete: (*client.Client).DeleteTag,
+}
+
+func runMeta(ctx context.Context, e *env, res resource, args []string) error {
+	name, args, err := subcommand(res.name, []string{"list", "get", "create", "update", "delete"}, args)
+	if err != nil {
+		return err
+	}
+
+	fs := newFlagSet(res.name + " " + name)
+	switch name {
+	case "list":
+		query := url.Values{}
+		for _, name := range []string{"include_archived", "limit", "offset"} {
+			name := name
+			fs.Func(strings.ReplaceAll(name, "_", "-"), "query parameter "+name+" of GET /"+res.name, func(value string) error {
+				query.Set(name, value)
+				return nil
+			})
+		}
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+
+		v, rows, err := res.list(ctx, e.client, query)
+		if err != nil {
+			return err
+		}
+		return e.out.print(v, res.header, rows)
+	case "get":
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		v, row, err := res.get(ctx, e.client, id)
+		if err != nil {
+			return err
+		}
+		return e.out.print(v, res.header, [][]string{row})
+	case "create":
+		meta := metaFlags(fs, res)
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		id, err := res.create(e.client, ctx, *meta)
+		if err != nil {
+			return err
+		}
+		return e.out.message(map[string]int64{res.name + "_id": id}, "created %s %d", res.name, id)
+	case "update":
+		force := fs.Bool("force", false, "archive even with active banners")
+		meta := metaFlags(fs, res)
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		if err := res.update(e.client, ctx, id, *meta, *force); err != nil {
+			return err
+		}
+		return e.out.message(map[string]int64{res.name + "_id": id}, "updated %s %d", res.name, id)
+	default:
+		force := fs.Bool("force", false, "delete even with active banners")
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		if err := res.delete(e.client, ctx, id, *force); err != nil {
+			return err
+		}
+		return e.out.message(map[string]int64{res.name + "_id": id}, "deleted %s %d", res.name, id)
+	}
+}
+
+// metaFlags defines flags of meta fields, flags that are not set stay nil
+func metaFlags(fs *flag.FlagSet, res resource) *client.MetaInput {
+	meta := &client.MetaInput{}
+
+	str := func(dst **string) func(string) error {
+		return func(value string) error {
+			*dst = &value
+			return nil
+		}
+	}
+	fs.Func("name", "unique name", str(&meta.Name))
+	fs.Func("description", "description", str(&meta.Description))
+	fs.Func("owner", "owner", str(&meta.Owner))
+	fs.Func("archived", "true or false", func(value string) error {
+		archived, err := strconv.ParseBool(value)
+		meta.Archived = &archived
+		return err
+	})
+	if res.name == tagResource.name {
+		fs.Func("parent-id", "parent tag, 0 makes tag top level", func(value string) error {
+			parentID, err := strconv.ParseInt(value, 10, 64)
+			meta.ParentID = &parentID
+			return err
+		})
+	}
+
+	return meta
+}
+
+func featureRow(feature *postgresql.Feature) []string {
+	defaultBanner := ""
+	if feature.DefaultBannerID != nil {
+		defaultBanner = fmt.Sprint(*feature.DefaultBannerID)
+	}
+
+	return []string{
+		fmt.Sprint(feature.FeatureID),
+		feature.Name,
+		feature.Owner,
+		fmt.Sprint(feature.Archived),
+		defaultBanner,
+		truncate(feature.Description, 40),
+	}
+}
+
+func tagRow(tag *postgresql.Tag) []string {
+	parent := ""
+	if tag.ParentID != nil {
+		parent = fmt.Sprint(*tag.ParentID)
+	}
+
+	return []string{
+		fmt.Sprint(tag.TagID),
+		tag.Name,
+		tag.Owner,
+		fmt.Sprint(tag.Archived),
+		parent,
+		truncate(tag.Description, 40),
+	}
+}
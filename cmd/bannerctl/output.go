@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type output struct {
+	w      io.Writer
+	format string
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case outputTable, outputJSON:
+		return &output{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output %q, must be table or json", format)
+	}
+}
+
+// print writes v as JSON or, for table output, header and rows
+func (o *output) print(v interface{}, header []string, rows [][]string) error {
+	if o.format == outputJSON {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// message writes result of command without data, e.g. deleted id
+func (o *output) message(v interface{}, format string, args ...interface{}) error {
+	if o.format == outputJSON {
+		return o.print(v, nil, nil)
+	}
+
+	_, err := fmt.Fprintf(o.w, format+"\n", args...)
+
+	return err
+}
+
+func ids(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// truncate shortens s for table cell
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+
+	return s
+}
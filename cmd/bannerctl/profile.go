@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func runProfile(cfg *Config, path string, args []string) error {
+	name, args, err := subcommand("profile", []string{"list", "use"}, args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		for _, profile := range cfg.Names() {
+			current := " "
+			if profile == cfg.Current {
+				current = "*"
+			}
+			fmt.Printf("%s %s\t%s\n", current, profile, cfg.Profiles[profile].URL)
+		}
+		return nil
+	default:
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: bannerctl profile use <name>")
+			return errUsage
+		}
+		if _, ok := cfg.Profiles[args[0]]; !ok {
+			return fmt.Errorf("profile %q is not found, known profiles: %v", args[0], cfg.Names())
+		}
+
+		cfg.Current = args[0]
+		if err := cfg.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("switched to profile %s\n", args[0])
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// runToken mints token for local development with jwt_secret of profile
+func runToken(e *env, args []string) error {
+	fs := newFlagSet("token")
+	username := fs.String("username", e.profile.Username, "username claim")
+	role := fs.String("role", e.profile.Role, "role claim: admin or user")
+	tag := fs.Int64("tag", e.profile.Tag, "tag claim of user")
+	ttl := fs.Duration("ttl", e.profile.TokenTTL, "lifetime of token, 0 is without expiration")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	token, err := e.profile.Mint(*username, *role, *tag, *ttl)
+	if err != nil {
+		return err
+	}
+
+	if e.out.format == outputJSON {
+		res := map[string]interface{}{"token": token}
+		if *ttl > 0 {
+			res["expires_at"] = time.Now().Add(*ttl).UTC().Format(time.RFC3339)
+		}
+		return e.out.print(res, nil, nil)
+	}
+
+	_, err = fmt.Fprintln(e.out.w, token)
+
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JustForWorld/banner-shift/internal/transfer"
+)
+
+var importHeader = []string{"LINE", "BANNER", "ACTION", "ERROR"}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", transfer.FormatJSONL, "jsonl, csv or yaml")
+	path := fs.String("out", "", "output file, default is stdout")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !transfer.Valid(*format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var w io.Writer = os.Stdout
+	if *path != "" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return e.client.Export(ctx, *format, w)
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("import [flags] <file>")
+	format := fs.String("format", "", "jsonl, csv or yaml, default is extension of file")
+	policy := fs.String("on-conflict", "skip", "banner holding tag and feature pair of record: skip, overwrite or fail")
+	matchByID := fs.Bool("match-by-id", false, "find existing banner by banner_id of record, for import into the same database")
+	dryRun := fs.Bool("dry-run", false, "report changes without applying them")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n", fs.Name())
+		return errUsage
+	}
+
+	path := args[0]
+	if *format == "" {
+		*format = transfer.FormatJSONL
+		if ext := extension(path); transfer.Valid(ext) {
+			*format = ext
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	report, err := e.client.Import(ctx, *format, r, *policy, *matchByID, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Items))
+	for _, item := range report.Items {
+		msg := item.Error
+		for _, detail := range item.Details {
+			msg += fmt.Sprintf("; %s: %s", detail.Field, detail.Message)
+		}
+		rows = append(rows, []string{fmt.Sprint(item.Line), fmt.Sprint(item.BannerID), item.Action, msg})
+	}
+	if err := e.out.print(report, importHeader, rows); err != nil {
+		return err
+	}
+	if e.out.format == outputTable {
+		fmt.Fprintf(e.out.w, "\ncreated: %d, updated: %d, skipped: %d, failed: %d",
+			report.Created, report.Updated, report.Skipped, report.Failed)
+		if report.DryRun {
+			fmt.Fprint(e.out.w, " (dry run)")
+		}
+		fmt.Fprintln(e.out.w)
+	}
+	if report.Aborted {
+		return fmt.Errorf("import is aborted, nothing is applied")
+	}
+
+	return nil
+}
+
+func extension(path string) string {
+	for i := len(path) - 1; i >= 0 && path[i] != '/'; i-- {
+		if path[i] == '.' {
+			if ext := path[i+1:]; ext == "yml" {
+				return transfer.FormatYAML
+			} else {
+				return ext
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,164 @@
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+const (
+	StatusIdle    = "idle"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+type Progress struct {
+	Status     string     `json:"status"`
+	Loaded     int64      `json:"loaded"`
+	Batches    int64      `json:"batches"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type BannerSource interface {
+	GetActiveBanners(ctx context.Context, afterID, limit int64) ([]storage.CacheEntry, int64, error)
+}
+
+type BannerCache interface {
+	SetBanners(ctx context.Context, entries []storage.CacheEntry) error
+}
+
+// Warmer loads active banners from PostgreSQL into Redis in batches,
+// only one warm-up runs at a time
+type Warmer struct {
+	log       *slog.Logger
+	source    BannerSource
+	cache     BannerCache
+	batchSize int64
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+func New(log *slog.Logger, source BannerSource, cache BannerCache, batchSize int) *Warmer {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	return &Warmer{
+		log:       log,
+		source:    source,
+		cache:     cache,
+		batchSize: int64(batchSize),
+		progress:  Progress{Status: StatusIdle},
+	}
+}
+
+func (w *Warmer) Progress() Progress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.progress
+}
+
+// Start runs warm-up in background, it reports false if one is already running
+func (w *Warmer) Start(ctx context.Context) (Progress, bool) {
+	if !w.begin() {
+		return w.Progress(), false
+	}
+
+	go w.run(ctx)
+
+	return w.Progress(), true
+}
+
+// Run warms up cache synchronously
+func (w *Warmer) Run(ctx context.Context) error {
+	if !w.begin() {
+		return fmt.Errorf("warm-up is already running")
+	}
+
+	return w.run(ctx)
+}
+
+func (w *Warmer) begin() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.progress.Status == StatusRunning {
+		return false
+	}
+
+	now := time.Now()
+	w.progress = Progress{Status: StatusRunning, StartedAt: &now}
+
+	return true
+}
+
+func (w *Warmer) run(ctx context.Context) error {
+	const op = "cache.warmup.Run"
+
+	log := w.log.With(slog.String("op", op))
+	log.Info("cache warm-up started")
+
+	var afterID int64
+	for {
+		entries, lastID, err := w.source.GetActiveBanners(ctx, afterID, w.batchSize)
+		if err != nil {
+			return w.finish(log, fmt.Errorf("%s: %w", op, err))
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		if err := w.cache.SetBanners(ctx, entries); err != nil {
+			return w.finish(log, fmt.Errorf("%s: %w", op, err))
+		}
+		afterID = lastID
+
+		w.mu.Lock()
+		w.progress.Loaded += int64(len(entries))
+		w.progress.Batches++
+		progress := w.progress
+		w.mu.Unlock()
+
+		log.Debug("cache warm-up batch loaded",
+			slog.Int64("batch", progress.Batches),
+			slog.Int64("loaded", progress.Loaded),
+		)
+
+		if int64(len(entries)) < w.batchSize {
+			break
+		}
+	}
+
+	return w.finish(log, nil)
+}
+
+func (w *Warmer) finish(log *slog.Logger, err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	w.progress.FinishedAt = &now
+	w.progress.Status = StatusDone
+	if err != nil {
+		w.progress.Status = StatusFailed
+		w.progress.Error = err.Error()
+		log.Error("cache warm-up failed", slog.String("error", err.Error()))
+		return err
+	}
+
+	log.Info("cache warm-up finished",
+		slog.Int64("loaded", w.progress.Loaded),
+		slog.Int64("batches", w.progress.Batches),
+	)
+
+	return nil
+}
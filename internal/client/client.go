@@ -0,0 +1,335 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+)
+
+// Client calls banner-shift HTTP API on behalf of one JWT token
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// APIError is non-2xx response of API
+type APIError struct {
+	Status  int
+	Message string
+	Details []schema.FieldError
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	for _, detail := range e.Details {
+		msg += fmt.Sprintf("\n  %s: %s", detail.Field, detail.Message)
+	}
+
+	return fmt.Sprintf("%d: %s", e.Status, msg)
+}
+
+type BannerPage struct {
+	Total      int64                `json:"total"`
+	Banners    []*postgresql.Banner `json:"banners"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	PrevCursor string               `json:"prev_cursor,omitempty"`
+}
+
+// BannerInput is body of banner create and update, nil fields are left as is on update
+type BannerInput struct {
+	TagIDs    []int64         `json:"tag_ids,omitempty"`
+	FeatureID int64           `json:"feature_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsActive  *bool           `json:"is_active,omitempty"`
+}
+
+// MetaInput is body of feature and tag create and update
+type MetaInput struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Owner       *string `json:"owner,omitempty"`
+	Archived    *bool   `json:"archived,omitempty"`
+	ParentID    *int64  `json:"parent_id,omitempty"`
+}
+
+type ImportItem struct {
+	Line     int                 `json:"line"`
+	BannerID int64               `json:"banner_id,omitempty"`
+	Action   string              `json:"action"`
+	Error    string              `json:"error,omitempty"`
+	Details  []schema.FieldError `json:"details,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool         `json:"dry_run"`
+	Policy    string       `json:"on_conflict"`
+	MatchByID bool         `json:"match_by_id"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Skipped   int          `json:"skipped"`
+	Failed    int          `json:"failed"`
+	Aborted   bool         `json:"aborted"`
+	Items     []ImportItem `json:"items"`
+}
+
+func New(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) ListBanners(ctx context.Context, query url.Values) (*BannerPage, error) {
+	var page BannerPage
+	if err := c.do(ctx, http.MethodGet, "/banner", query, nil, nil, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func (c *Client) GetBanner(ctx context.Context, id int64) (*postgresql.Banner, error) {
+	var banner postgresql.Banner
+	if err := c.do(ctx, http.MethodGet, "/banner/"+strconv.FormatInt(id, 10), nil, nil, nil, &banner); err != nil {
+		return nil, err
+	}
+
+	return &banner, nil
+}
+
+func (c *Client) CreateBanner(ctx context.Context, banner BannerInput) (int64, error) {
+	var res struct {
+		BannerID int64 `json:"banner_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/banner", nil, nil, banner, &res); err != nil {
+		return 0, err
+	}
+
+	return res.BannerID, nil
+}
+
+// UpdateBanner patches banner, with version > 0 only if it still matches
+func (c *Client) UpdateBanner(ctx context.Context, id int64, banner BannerInput, version int64) error {
+	return c.do(ctx, http.MethodPatch, "/banner/"+strconv.FormatInt(id, 10), nil, ifMatch(version), banner, nil)
+}
+
+// DeleteBanner removes banner, with version > 0 only if it still matches
+func (c *Client) DeleteBanner(ctx context.Context, id int64, version int64) error {
+	return c.do(ctx, http.MethodDelete, "/banner/"+strconv.FormatInt(id, 10), nil, ifMatch(version), nil, nil)
+}
+
+func (c *Client) ListFeatures(ctx context.Context, query url.Values) ([]*postgresql.Feature, error) {
+	var features []*postgresql.Feature
+	if err := c.do(ctx, http.MethodGet, "/feature", query, nil, nil, &features); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+func (c *Client) GetFeature(ctx context.Context, id int64) (*postgresql.Feature, error) {
+	var feature postgresql.Feature
+	if err := c.do(ctx, http.MethodGet, "/feature/"+strconv.FormatInt(id, 10), nil, nil, nil, &feature); err != nil {
+		return nil, err
+	}
+
+	return &feature, nil
+}
+
+func (c *Client) CreateFeature(ctx context.Context, meta MetaInput) (int64, error) {
+	var res struct {
+		FeatureID int64 `json:"feature_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/feature", nil, nil, meta, &res); err != nil {
+		return 0, err
+	}
+
+	return res.FeatureID, nil
+}
+
+func (c *Client) UpdateFeature(ctx context.Context, id int64, meta MetaInput, force bool) error {
+	return c.do(ctx, http.MethodPatch, "/feature/"+strconv.FormatInt(id, 10), forceQuery(force), nil, meta, nil)
+}
+
+func (c *Client) DeleteFeature(ctx context.Context, id int64, force bool) error {
+	return c.do(ctx, http.MethodDelete, "/feature/"+strconv.FormatInt(id, 10), forceQuery(force), nil, nil, nil)
+}
+
+func (c *Client) ListTags(ctx context.Context, query url.Values) ([]*postgresql.Tag, error) {
+	var tags []*postgresql.Tag
+	if err := c.do(ctx, http.MethodGet, "/tag", query, nil, nil, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (c *Client) GetTag(ctx context.Context, id int64) (*postgresql.Tag, error) {
+	var tag postgresql.Tag
+	if err := c.do(ctx, http.MethodGet, "/tag/"+strconv.FormatInt(id, 10), nil, nil, nil, &tag); err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (c *Client) CreateTag(ctx context.Context, meta MetaInput) (int64, error) {
+	var res struct {
+		TagID int64 `json:"tag_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/tag", nil, nil, meta, &res); err != nil {
+		return 0, err
+	}
+
+	return res.TagID, nil
+}
+
+func (c *Client) UpdateTag(ctx context.Context, id int64, meta MetaInput, force bool) error {
+	return c.do(ctx, http.MethodPatch, "/tag/"+strconv.FormatInt(id, 10), forceQuery(force), nil, meta, nil)
+}
+
+func (c *Client) DeleteTag(ctx context.Context, id int64, force bool) error {
+	return c.do(ctx, http.MethodDelete, "/tag/"+strconv.FormatInt(id, 10), forceQuery(force), nil, nil, nil)
+}
+
+// Export copies banners in format jsonl, csv or yaml to w
+func (c *Client) Export(ctx context.Context, format string, w io.Writer) error {
+	res, err := c.send(ctx, http.MethodGet, "/banner/export", url.Values{"format": {format}}, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+
+	return err
+}
+
+// Import sends banners in format jsonl, csv or yaml, report is returned for aborted import too
+func (c *Client) Import(ctx context.Context, format string, r io.Reader, policy string, matchByID, dryRun bool) (*ImportReport, error) {
+	query := url.Values{
+		"format":      {format},
+		"on_conflict": {policy},
+		"match_by_id": {strconv.FormatBool(matchByID)},
+		"dry_run":     {strconv.FormatBool(dryRun)},
+	}
+
+	res, err := c.send(ctx, http.MethodPost, "/banner/import", query, nil, r, "application/octet-stream")
+	if apiErr, ok := err.(*APIError); ok && apiErr.Status == http.StatusConflict {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var report ImportReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &report, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	res, err := c.send(ctx, method, path, query, header, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// send returns response with status 2xx, otherwise body is decoded into APIError;
+// for 409 of import response body is kept open
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, header http.Header, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+
+	data, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	apiErr := &APIError{Status: res.StatusCode}
+	var errRes resp.ValidationResponse
+	if json.Unmarshal(data, &errRes) == nil {
+		apiErr.Message = errRes.Error
+		apiErr.Details = errRes.Details
+	}
+
+	return res, apiErr
+}
+
+func ifMatch(version int64) http.Header {
+	if version <= 0 {
+		return nil
+	}
+
+	return http.Header{"If-Match": {etag.Version(version)}}
+}
+
+func forceQuery(force bool) url.Values {
+	if !force {
+		return nil
+	}
+
+	return url.Values{"force": {"true"}}
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/http-server/handlers/banner/save"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+type bannerStore struct {
+	id   int64
+	keys map[string]*storage.IdempotentResponse
+}
+
+func (s *bannerStore) CreateBanner(ctx context.Context, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, time.Time, error) {
+	if len(tagIDs) == 0 {
+		return 0, time.Time{}, storage.ErrBannerInvalidData
+	}
+	s.id++
+
+	return s.id, time.Now(), nil
+}
+
+func (s *bannerStore) SetBanner(ctx context.Context, tagID, featureID int64, banner *storage.ResolvedBanner) error {
+	return nil
+}
+
+func (s *bannerStore) FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error) {
+	return 0, nil
+}
+
+func (s *bannerStore) ValidateContent(ctx context.Context, featureID int64, content []byte) ([]schema.FieldError, error) {
+	return nil, nil
+}
+
+func (s *bannerStore) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (string, *storage.IdempotentResponse, error) {
+	if stored := s.keys[key]; stored != nil {
+		return "", stored, nil
+	}
+
+	return "owner", nil, nil
+}
+
+func (s *bannerStore) CreateBannerIdempotent(ctx context.Context, key, owner string, featureID int64, tagIDs []int, content []byte, isActive bool, respond func(bannerID int64) (*storage.IdempotentResponse, error)) (int64, time.Time, error) {
+	bannerID, createdAt, err := s.CreateBanner(ctx, featureID, tagIDs, content, isActive)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	response, err := respond(bannerID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	s.keys[key] = response
+
+	return bannerID, createdAt, nil
+}
+
+func (s *bannerStore) ReleaseIdempotencyKey(ctx context.Context, key, owner string) error {
+	return nil
+}
+
+// newServer serves POST /banner with real handler, so client is checked against actual response body
+func newServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	tokenAuth := jwtauth.New("HS256", []byte("test-secret"), nil)
+	_, token, err := tokenAuth.Encode(map[string]interface{}{"username": "admin", "role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &bannerStore{keys: map[string]*storage.IdempotentResponse{}}
+
+	router := chi.NewRouter()
+	router.Use(jwtauth.Verifier(tokenAuth))
+	router.Use(jwtauth.Authenticator(tokenAuth))
+	router.Post("/banner", save.New(log, store, store, store, store, time.Hour))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, token
+}
+
+func TestCreateBanner(t *testing.T) {
+	server, token := newServer(t)
+	c := New(server.URL, token, time.Second)
+
+	for want := int64(1); want <= 2; want++ {
+		id, err := c.CreateBanner(context.Background(), BannerInput{TagIDs: []int64{1, 2}, FeatureID: 3, Content: []byte(`{"title": "t"}`)})
+		if err != nil {
+			t.Fatalf("CreateBanner: %v", err)
+		}
+		if id != want {
+			t.Errorf("CreateBanner = %d, want %d", id, want)
+		}
+	}
+}
+
+func TestCreateBannerReplay(t *testing.T) {
+	server, token := newServer(t)
+	c := New(server.URL, token, time.Second)
+
+	// replayed body is stored response of first request, client must decode it the same way
+	header := http.Header{"Idempotency-Key": {"key"}}
+	body := BannerInput{TagIDs: []int64{1}, FeatureID: 1}
+	for i := 0; i < 2; i++ {
+		var res struct {
+			BannerID int64 `json:"banner_id"`
+		}
+		if err := c.do(context.Background(), http.MethodPost, "/banner", nil, header, body, &res); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if res.BannerID != 1 {
+			t.Errorf("request %d: banner_id = %d, want 1", i, res.BannerID)
+		}
+	}
+}
+
+func TestCreateBannerError(t *testing.T) {
+	server, token := newServer(t)
+	c := New(server.URL, token, time.Second)
+
+	_, err := c.CreateBanner(context.Background(), BannerInput{FeatureID: 1})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateBanner error = %v, want APIError", err)
+	}
+	if apiErr.Status != http.StatusBadRequest || apiErr.Message != "Некорректные данные" {
+		t.Errorf("CreateBanner error = %d %q", apiErr.Status, apiErr.Message)
+	}
+
+	_, err = New(server.URL, "", time.Second).CreateBanner(context.Background(), BannerInput{TagIDs: []int64{1}})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("CreateBanner without token error = %v, want 401", err)
+	}
+}
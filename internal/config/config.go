@@ -3,39 +3,156 @@ package config
 import (
 	"flag"
 	"log"
-	"os"
 	"time"
-
-	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env        string `yaml:"env" env-default:"dev"`
+	// Files are config files the config is read from, in order of layering
+	Files []string `yaml:"-"`
+	Env   string   `yaml:"env" env-default:"dev"`
+	// LogLevel is one of debug, info, warn, error, empty level is chosen by env
+	LogLevel   string `yaml:"log_level" reload:"true"`
+	Reload     `yaml:"reload"`
 	HTTPServer `yaml:"http_server"`
 	PostgreSQL `yaml:"postgres"`
 	Redis      `yaml:"redis"`
+	RateLimit  `yaml:"rate_limit"`
+}
+
+// Reload of settings tagged reload is triggered by SIGHUP or, with Watch, by
+// changed config files; other settings still require restart
+type Reload struct {
+	Watch    bool          `yaml:"watch" env-default:"false"`
+	Interval time.Duration `yaml:"interval" env-default:"5s"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	Pagination  `yaml:"pagination"`
+	// IdempotencyTTL is how long responses of POST /banner are kept for Idempotency-Key
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" env-default:"24h"`
+	// JWTSecret signs HS256 tokens, empty secret is generated on every start
+	JWTSecret string `yaml:"jwt_secret" secret:"true"`
+}
+
+type Pagination struct {
+	DefaultLimit int64 `yaml:"default_limit" env-default:"20"`
+	MaxLimit     int64 `yaml:"max_limit" env-default:"100"`
 }
 
 type PostgreSQL struct {
 	Host     string `yaml:"host" env-default:"localhost"`
 	Port     int    `yaml:"port" env-default:"5432"`
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	DB       string `yaml:"db" env-default:"postgres"`
+	// SSLMode is one of disable, require, verify-ca, verify-full
+	SSLMode          string        `yaml:"sslmode" env-default:"disable"`
+	SSLRootCert      string        `yaml:"sslrootcert"`
+	SSLCert          string        `yaml:"sslcert"`
+	SSLKey           string        `yaml:"sslkey"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" env-default:"0s"`
+	PostgreSQLPool   `yaml:"pool"`
+	// ReplicaDSN is optional read replica for GET /banner and GET /user_banner
+	ReplicaDSN string `yaml:"replica_dsn" secret:"true"`
+	Search     Search `yaml:"search"`
+}
+
+// Search configures full-text search of GET /banner/search, changing it builds a new index
+type Search struct {
+	Fields   []string `yaml:"fields" env-default:"title,text"`
+	Language string   `yaml:"language" env-default:"simple"`
+}
+
+type PostgreSQLPool struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" env-default:"0"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env-default:"2"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"0s"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env-default:"0s"`
 }
 
 type Redis struct {
-	Addr     string `yaml:"addr"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DB       int    `yaml:"db"`
-	Protocol int    `yaml:"protocol"`
+	// Mode is one of standalone, sentinel, cluster
+	Mode string `yaml:"mode" env-default:"standalone"`
+	Addr string `yaml:"addr" env-default:"localhost:6379"`
+	// Addrs are sentinel addresses in sentinel mode and seed nodes in cluster mode
+	Addrs            []string  `yaml:"addrs"`
+	MasterName       string    `yaml:"master_name"`
+	User             string    `yaml:"user"`
+	Password         string    `yaml:"password" secret:"true"`
+	SentinelUser     string    `yaml:"sentinel_user"`
+	SentinelPassword string    `yaml:"sentinel_password" secret:"true"`
+	DB               int       `yaml:"db"`
+	Protocol         int       `yaml:"protocol"`
+	KeyPrefix        string    `yaml:"key_prefix"`
+	Pool             RedisPool `yaml:"pool"`
+	TLS              RedisTLS  `yaml:"tls"`
+	Cache            `yaml:"cache"`
+	Breaker          `yaml:"breaker"`
+	Warmup           `yaml:"warmup"`
+}
+
+// Addresses returns Addrs, falling back to single Addr
+func (r *Redis) Addresses() []string {
+	if len(r.Addrs) > 0 {
+		return r.Addrs
+	}
+	if r.Addr != "" {
+		return []string{r.Addr}
+	}
+
+	return nil
+}
+
+type RedisPool struct {
+	Size         int           `yaml:"size" env-default:"10"`
+	MinIdleConns int           `yaml:"min_idle_conns" env-default:"0"`
+	DialTimeout  time.Duration `yaml:"dial_timeout" env-default:"5s"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env-default:"3s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env-default:"3s"`
+}
+
+type RedisTLS struct {
+	Enabled            bool   `yaml:"enabled" env-default:"false"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" env-default:"false"`
+}
+
+type Cache struct {
+	BannerTTL   time.Duration `yaml:"banner_ttl" env-default:"5m" reload:"true"`
+	NotFoundTTL time.Duration `yaml:"not_found_ttl" env-default:"30s" reload:"true"`
+	KeyVersion  int           `yaml:"key_version" env-default:"3"`
+}
+
+type Breaker struct {
+	FailureThreshold int           `yaml:"failure_threshold" env-default:"5"`
+	OpenTimeout      time.Duration `yaml:"open_timeout" env-default:"10s"`
+	ProbeInterval    time.Duration `yaml:"probe_interval" env-default:"5s"`
+}
+
+type Warmup struct {
+	OnStartup bool `yaml:"on_startup" env-default:"false"`
+	BatchSize int  `yaml:"batch_size" env-default:"500"`
+}
+
+// RateLimit is token bucket per JWT subject or client IP
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" env-default:"false" reload:"true"`
+	// Mode is memory for single node or redis to share limits across replicas
+	Mode       string `yaml:"mode" env-default:"memory"`
+	UserBanner Limit  `yaml:"user_banner" reload:"true"`
+	Admin      Limit  `yaml:"admin" reload:"true"`
+}
+
+// Limit allows Burst requests at once and Rate requests per second on average
+type Limit struct {
+	Rate  float64 `yaml:"rate" env-default:"50"`
+	Burst int     `yaml:"burst" env-default:"100"`
 }
 
 type User struct {
@@ -44,30 +161,28 @@ type User struct {
 	Role     string `yaml:"role"`
 }
 
+// MustLoad reads config from files of repeated --config flag and user from --user,
+// see Load for the order of sources
 func MustLoad() (*Config, *User) {
-	// TODO: remove default value
-	configPath := flag.String("config", "./config/local.yaml", "for initiate configuration")
+	files := Files{}
+	flag.Var(&files, "config", "config file, repeat to layer files, later files override earlier ones")
 	userPath := flag.String("user", "./config/mock/user.yaml", "for initiate default user")
 	flag.Parse()
 
-	if *configPath == "" {
-		log.Fatal(`"config" is not set`)
+	cfg, usr, err := Load(files, *userPath)
+	if err != nil {
+		log.Fatalf("invalid config:\n%s", err)
 	}
 
-	// check if file exists
-	if _, err := os.Stat(*configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", *configPath)
-	}
-
-	var cfg Config
-	if err := cleanenv.ReadConfig(*configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
-	}
+	return cfg, usr
+}
 
-	var usr User
-	if err := cleanenv.ReadConfig(*userPath, &usr); err != nil {
-		log.Fatalf("cannot read user config: %s", err)
+// MustLoadFiles reads config without flags, for subcommands with flags of their own
+func MustLoadFiles(files []string) *Config {
+	cfg, _, err := Load(files, "")
+	if err != nil {
+		log.Fatalf("invalid config:\n%s", err)
 	}
 
-	return &cfg, &usr
+	return cfg
 }
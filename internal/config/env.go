@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a leaf of config, key is its YAML path and env its variable name
+type setting struct {
+	key    string
+	env    string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// settings lists leaves of struct v in order of fields, nested structs
+// (embedded ones too) are named by their yaml tag, reload tag of struct
+// applies to all its leaves
+func settings(prefix, path string, reload bool, v reflect.Value) []setting {
+	var list []setting
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		key := name
+		if path != "" {
+			key = path + "." + name
+		}
+		env := prefix + "_" + strings.ToUpper(name)
+
+		value := v.Field(i)
+		reload := reload || field.Tag.Get("reload") == "true"
+		if field.Type.Kind() == reflect.Struct {
+			list = append(list, settings(env, key, reload, value)...)
+			continue
+		}
+		list = append(list, setting{
+			key:    key,
+			env:    env,
+			secret: field.Tag.Get("secret") == "true",
+			reload: reload,
+			value:  value,
+		})
+	}
+
+	return list
+}
+
+// readEnv overrides settings of v from env variables, NAME_FILE variables
+// give path of file with the value, e.g. BANNER_POSTGRES_PASSWORD_FILE
+func readEnv(prefix string, v interface{}) []error {
+	var errs []error
+
+	for _, s := range settings(prefix, "", false, reflect.ValueOf(v).Elem()) {
+		raw, fromEnv := os.LookupEnv(s.env)
+		if path, ok := os.LookupEnv(s.env + "_FILE"); ok {
+			if fromEnv {
+				errs = append(errs, fmt.Errorf("%s and %s_FILE are both set", s.env, s.env))
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", s.env, err))
+				continue
+			}
+			raw, fromEnv = strings.TrimRight(string(data), "\r\n"), true
+		}
+		if !fromEnv {
+			continue
+		}
+
+		if err := setValue(s.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+
+	return errs
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
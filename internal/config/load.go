@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// envPrefix starts names of env variables overriding settings, e.g. BANNER_POSTGRES_PASSWORD
+	envPrefix = "BANNER"
+	// envConfig lists config files when --config is not set
+	envConfig = "BANNER_CONFIG"
+	// defaultFile is read when no config file is given and it exists
+	defaultFile = "./config/local.yaml"
+)
+
+// Files is value of repeated --config flag, comma separated lists are split
+type Files []string
+
+func (f *Files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *Files) Set(value string) error {
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			*f = append(*f, path)
+		}
+	}
+
+	return nil
+}
+
+// Load builds config from layers, each one overriding the previous:
+// defaults, files in order (BANNER_CONFIG or ./config/local.yaml when files
+// is empty), BANNER_* env variables and their BANNER_*_FILE variants.
+// User is read the same way from userPath and BANNER_USER_* variables.
+// All errors of files, env and validation are reported at once
+func Load(files []string, userPath string) (*Config, *User, error) {
+	var errs []error
+
+	if len(files) == 0 {
+		var fromEnv Files
+		_ = fromEnv.Set(os.Getenv(envConfig))
+		files = fromEnv
+	}
+	if len(files) == 0 {
+		if _, err := os.Stat(defaultFile); err == nil {
+			files = []string{defaultFile}
+		}
+	}
+
+	var cfg Config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		errs = append(errs, fmt.Errorf("defaults: %w", err))
+	}
+	for _, path := range files {
+		errs = append(errs, readFile(path, &cfg)...)
+	}
+	errs = append(errs, readEnv(envPrefix, &cfg)...)
+	cfg.Files = files
+
+	var usr User
+	if userPath != "" {
+		errs = append(errs, readFile(userPath, &usr)...)
+	}
+	errs = append(errs, readEnv(envPrefix+"_USER", &usr)...)
+
+	// settings that failed to parse keep previous layer, so the rest is still validated
+	errs = append(errs, cfg.Validate()...)
+	if userPath != "" {
+		errs = append(errs, usr.Validate()...)
+	}
+	if len(errs) > 0 {
+		return nil, nil, joinErrors(errs)
+	}
+
+	return &cfg, &usr, nil
+}
+
+// readFile decodes YAML file over v, only keys present in file are changed,
+// unknown keys are errors
+func readFile(path string, v interface{}) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{err}
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make([]error, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			errs[i] = fmt.Errorf("%s: %s", filepath.Clean(path), msg)
+		}
+		return errs
+	}
+	if err != nil {
+		return []error{fmt.Errorf("%s: %w", filepath.Clean(path), err)}
+	}
+
+	return nil
+}
+
+// joinErrors lists errors one per line
+func joinErrors(errs []error) error {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = "  - " + err.Error()
+	}
+
+	return errors.New(strings.Join(msgs, "\n"))
+}
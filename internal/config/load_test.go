@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile creates file with content in temporary directory of test
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv(envConfig, "")
+
+	cfg, usr, err := Load(nil, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.Env != "dev" || cfg.Address != "localhost:8080" || cfg.Timeout != 4*time.Second {
+		t.Errorf("defaults are not set: env %q, address %q, timeout %s", cfg.Env, cfg.Address, cfg.Timeout)
+	}
+	if cfg.Redis.Addr != "localhost:6379" || cfg.Redis.Cache.BannerTTL != 5*time.Minute {
+		t.Errorf("defaults are not set: redis addr %q, banner_ttl %s", cfg.Redis.Addr, cfg.Redis.Cache.BannerTTL)
+	}
+	if *usr != (User{}) {
+		t.Errorf("user = %+v, want empty", *usr)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	base := writeFile(t, "base.yaml", `
+env: prod
+http_server:
+  address: ":8080"
+  timeout: 10s
+postgres:
+  host: db
+  password: from-file
+`)
+	override := writeFile(t, "override.yaml", `
+http_server:
+  timeout: 2s
+redis:
+  cache:
+    banner_ttl: 1m
+`)
+	secret := writeFile(t, "jwt_secret", "from-secret-file\n")
+	t.Setenv("BANNER_POSTGRES_HOST", "db-from-env")
+	t.Setenv("BANNER_POSTGRES_SEARCH_FIELDS", "title, text,url")
+	t.Setenv("BANNER_HTTP_SERVER_JWT_SECRET_FILE", secret)
+
+	cfg, _, err := Load([]string{base, override}, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		got  interface{}
+		want interface{}
+	}{
+		{"env", cfg.Env, "prod"},
+		{"http_server.address", cfg.Address, ":8080"},
+		{"http_server.timeout", cfg.Timeout, 2 * time.Second},
+		{"http_server.idle_timeout", cfg.IdleTimeout, 60 * time.Second},
+		{"http_server.jwt_secret", cfg.JWTSecret, "from-secret-file"},
+		{"postgres.host", cfg.PostgreSQL.Host, "db-from-env"},
+		{"postgres.password", cfg.PostgreSQL.Password, "from-file"},
+		{"postgres.search.fields", cfg.PostgreSQL.Search.Fields, []string{"title", "text", "url"}},
+		{"redis.cache.banner_ttl", cfg.Redis.Cache.BannerTTL, time.Minute},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+	}
+	if !reflect.DeepEqual(cfg.Files, []string{base, override}) {
+		t.Errorf("files = %v", cfg.Files)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	first := writeFile(t, "first.yaml", "env: prod\n")
+	second := writeFile(t, "second.yaml", "log_level: debug\n")
+	t.Setenv(envConfig, first+","+second)
+
+	cfg, _, err := Load(nil, "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Env != "prod" || cfg.LogLevel != "debug" {
+		t.Errorf("env %q, log_level %q, want prod, debug", cfg.Env, cfg.LogLevel)
+	}
+}
+
+func TestLoadUser(t *testing.T) {
+	t.Setenv(envConfig, "")
+	path := writeFile(t, "user.yaml", "username: admin\nrole: admin\ntag: 3\n")
+	t.Setenv("BANNER_USER_TAG", "5")
+
+	_, usr, err := Load(nil, path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if want := (User{Username: "admin", Role: "admin", Tag: 5}); *usr != want {
+		t.Errorf("user = %+v, want %+v", *usr, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	path := writeFile(t, "invalid.yaml", `
+env: staging
+http_server:
+  timeout: soon
+  unknown: 1
+rate_limit:
+  admin:
+    rate: 0
+`)
+	t.Setenv("BANNER_POSTGRES_PORT", "not-a-port")
+	t.Setenv("BANNER_REDIS_PASSWORD", "secret")
+	t.Setenv("BANNER_REDIS_PASSWORD_FILE", "/nonexistent")
+
+	_, _, err := Load([]string{path}, "")
+	if err == nil {
+		t.Fatal("Load of invalid config succeeded")
+	}
+
+	// every error is reported, not only the first one
+	for _, want := range []string{
+		"field unknown not found",
+		"cannot unmarshal !!str `soon`",
+		"BANNER_POSTGRES_PORT",
+		"BANNER_REDIS_PASSWORD and BANNER_REDIS_PASSWORD_FILE are both set",
+		`env: "staging" must be one of`,
+		"rate_limit.admin.rate: must be positive",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, _, err := Load([]string{filepath.Join(t.TempDir(), "missing.yaml")}, "")
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("Load error = %v, want error of missing file", err)
+	}
+}
+
+func TestFilesFlag(t *testing.T) {
+	var files Files
+	for _, value := range []string{"a.yaml", "b.yaml, c.yaml", ","} {
+		if err := files.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := (Files{"a.yaml", "b.yaml", "c.yaml"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces set secrets in printed config
+const redacted = "<redacted>"
+
+// Print writes effective config as YAML, values of secret settings are redacted
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if len(c.Files) > 0 {
+		root.HeadComment = "files: " + strings.Join(c.Files, ", ")
+	}
+
+	for _, s := range settings(envPrefix, "", false, reflect.ValueOf(c).Elem()) {
+		value := &yaml.Node{}
+		if err := value.Encode(printValue(s)); err != nil {
+			return err
+		}
+		if value.Kind == yaml.SequenceNode {
+			value.Style = yaml.FlowStyle
+		}
+		value.LineComment = s.env
+
+		parent := root
+		path := strings.Split(s.key, ".")
+		for _, name := range path[:len(path)-1] {
+			parent = child(parent, name)
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: path[len(path)-1]}, value)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func printValue(s setting) interface{} {
+	if s.secret {
+		if s.value.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+	if s.value.Type() == durationType {
+		return fmt.Sprint(s.value.Interface())
+	}
+	if s.value.Kind() == reflect.Slice && s.value.IsNil() {
+		return []string{}
+	}
+
+	return s.value.Interface()
+}
+
+// child returns mapping of parent by key, it is added if missing
+func child(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+
+	return node
+}
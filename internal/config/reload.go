@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// reloads counts config reloads by result, it is served on /debug/vars
+var (
+	reloads    = expvar.NewMap("config_reloads")
+	lastReload = new(expvar.String)
+)
+
+func init() {
+	reloads.Set("last_success", lastReload)
+}
+
+// Reloader holds current config and reloads settings tagged reload from the
+// same files on SIGHUP or on change of files, invalid config is rejected as a
+// whole and the current one is kept
+type Reloader struct {
+	log     *slog.Logger
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	appliers []func(*Config)
+	modTimes map[string]time.Time
+}
+
+func NewReloader(log *slog.Logger, cfg *Config) *Reloader {
+	r := &Reloader{log: log}
+	r.current.Store(cfg)
+	r.modTimes = r.stat()
+
+	return r
+}
+
+// Config returns current config, it must not be modified
+func (r *Reloader) Config() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn applying reloaded config to a running component
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appliers = append(r.appliers, fn)
+}
+
+// Run reloads config on SIGHUP and, with reload.watch, on change of files until ctx is done
+func (r *Reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if cfg := r.Config(); cfg.Reload.Watch {
+		ticker := time.NewTicker(cfg.Reload.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			_ = r.Reload("signal")
+		case <-tick:
+			if r.changed() {
+				_ = r.Reload("file")
+			}
+		}
+	}
+}
+
+// Reload reads and validates config files, then swaps reloadable settings
+// and applies them; changes of other settings are logged and ignored
+func (r *Reloader) Reload(trigger string) error {
+	const op = "config.Reloader.Reload"
+
+	log := r.log.With(slog.String("op", op), slog.String("trigger", trigger))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.current.Load()
+	loaded, _, err := Load(current.Files, "")
+	if err != nil {
+		reloads.Add("failed", 1)
+		log.Error("invalid config, keeping current one", slog.String("error", err.Error()))
+		return err
+	}
+
+	next, changed, ignored := merge(current, loaded)
+	if len(ignored) > 0 {
+		log.Warn("changed settings require restart", slog.Any("settings", ignored))
+	}
+
+	r.current.Store(next)
+	for _, apply := range r.appliers {
+		apply(next)
+	}
+
+	reloads.Add("succeeded", 1)
+	lastReload.Set(time.Now().UTC().Format(time.RFC3339))
+	log.Info("config reloaded", slog.Any("changed", changed))
+
+	return nil
+}
+
+// merge returns copy of current with reloadable settings of loaded and keys of
+// changed reloadable and ignored settings
+func merge(current, loaded *Config) (*Config, []string, []string) {
+	next := *current
+	var changed, ignored []string
+
+	from := settings(envPrefix, "", false, reflect.ValueOf(loaded).Elem())
+	for i, s := range settings(envPrefix, "", false, reflect.ValueOf(&next).Elem()) {
+		if reflect.DeepEqual(s.value.Interface(), from[i].value.Interface()) {
+			continue
+		}
+		if !s.reload {
+			ignored = append(ignored, s.key)
+			continue
+		}
+		s.value.Set(from[i].value)
+		changed = append(changed, s.key)
+	}
+
+	return &next, changed, ignored
+}
+
+// changed reports whether modification time of any config file changed
+func (r *Reloader) changed() bool {
+	modTimes := r.stat()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := !reflect.DeepEqual(modTimes, r.modTimes)
+	r.modTimes = modTimes
+
+	return changed
+}
+
+func (r *Reloader) stat() map[string]time.Time {
+	files := r.Config().Files
+	modTimes := make(map[string]time.Time, len(files))
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+
+	return modTimes
+}
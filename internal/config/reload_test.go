@@ -0,0 +1,94 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	current := &Config{Files: []string{"a.yaml"}, Env: "prod", LogLevel: "info"}
+	current.Address = ":8080"
+	current.Redis.Cache.BannerTTL = 5 * time.Minute
+	current.RateLimit.Admin = Limit{Rate: 10, Burst: 20}
+
+	loaded := *current
+	loaded.LogLevel = "debug"
+	loaded.Address = ":9090"
+	loaded.Redis.Cache.BannerTTL = time.Minute
+	loaded.RateLimit.Admin.Burst = 5
+
+	next, changed, ignored := merge(current, &loaded)
+
+	if want := []string{"log_level", "redis.cache.banner_ttl", "rate_limit.admin.burst"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"http_server.address"}; !reflect.DeepEqual(ignored, want) {
+		t.Errorf("ignored = %v, want %v", ignored, want)
+	}
+
+	if next.LogLevel != "debug" || next.Redis.Cache.BannerTTL != time.Minute || next.RateLimit.Admin != (Limit{Rate: 10, Burst: 5}) {
+		t.Errorf("reloadable settings are not applied: %+v", next)
+	}
+	if next.Address != ":8080" || next.Env != "prod" {
+		t.Errorf("settings requiring restart are changed: address %q, env %q", next.Address, next.Env)
+	}
+
+	// current config is shared with running components and is never modified
+	if current.LogLevel != "info" || current.Redis.Cache.BannerTTL != 5*time.Minute {
+		t.Errorf("current config is modified: %+v", current)
+	}
+}
+
+func TestMergeUnchanged(t *testing.T) {
+	current := &Config{Env: "prod"}
+	loaded := *current
+
+	if _, changed, ignored := merge(current, &loaded); len(changed) != 0 || len(ignored) != 0 {
+		t.Errorf("changed = %v, ignored = %v, want none", changed, ignored)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeFile(t, "config.yaml", "env: prod\nlog_level: info\n")
+	cfg, _, err := Load([]string{path}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReloader(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	var applied []*Config
+	r.OnReload(func(cfg *Config) {
+		applied = append(applied, cfg)
+	})
+
+	if err := os.WriteFile(path, []byte("env: dev\nlog_level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload("test"); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := r.Config(); got.LogLevel != "debug" || got.Env != "prod" {
+		t.Errorf("reloaded log_level %q, env %q, want debug, prod", got.LogLevel, got.Env)
+	}
+	if len(applied) != 1 || applied[0] != r.Config() {
+		t.Errorf("reloaded config is applied %d times", len(applied))
+	}
+
+	// invalid config is rejected as a whole
+	if err := os.WriteFile(path, []byte("log_level: warn\nhttp_server:\n  timeout: 0s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload("test"); err == nil {
+		t.Fatal("Reload of invalid config succeeded")
+	}
+	if got := r.Config(); got.LogLevel != "debug" {
+		t.Errorf("log_level = %q after rejected reload, want debug", got.LogLevel)
+	}
+	if len(applied) != 1 {
+		t.Errorf("rejected config is applied")
+	}
+}
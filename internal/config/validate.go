@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var searchFieldRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// validator collects errors of settings named by YAML path
+type validator []error
+
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		*v = append(*v, fmt.Errorf("%s: "+format, append([]interface{}{key}, args...)...))
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.check(false, key, "%q must be one of %v", value, allowed)
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "must be positive, got %s", d)
+}
+
+func (v *validator) notNegative(key string, n int64) {
+	v.check(n >= 0, key, "must not be negative, got %d", n)
+}
+
+func (v *validator) limit(key string, limit Limit) {
+	v.check(limit.Rate > 0, key+".rate", "must be positive, got %g", limit.Rate)
+	v.check(limit.Burst >= 1, key+".burst", "must be at least 1, got %d", limit.Burst)
+}
+
+// Validate returns all invalid settings of config
+func (c *Config) Validate() []error {
+	var v validator
+
+	v.oneOf("env", c.Env, "local", "dev", "prod")
+	v.oneOf("log_level", c.LogLevel, "", "debug", "info", "warn", "error")
+	if c.Reload.Watch {
+		v.positive("reload.interval", c.Reload.Interval)
+	}
+
+	v.check(c.HTTPServer.Address != "", "http_server.address", "is required")
+	v.positive("http_server.timeout", c.HTTPServer.Timeout)
+	v.notNegative("http_server.idle_timeout", int64(c.HTTPServer.IdleTimeout))
+	v.check(c.Pagination.DefaultLimit > 0, "http_server.pagination.default_limit", "must be positive, got %d", c.Pagination.DefaultLimit)
+	v.check(c.Pagination.MaxLimit == 0 || c.Pagination.MaxLimit >= c.Pagination.DefaultLimit,
+		"http_server.pagination.max_limit", "must be 0 (no limit) or at least default_limit %d, got %d", c.Pagination.DefaultLimit, c.Pagination.MaxLimit)
+	v.positive("http_server.idempotency_ttl", c.HTTPServer.IdempotencyTTL)
+
+	v.check(c.PostgreSQL.Host != "", "postgres.host", "is required")
+	v.check(c.PostgreSQL.Port > 0 && c.PostgreSQL.Port < 65536, "postgres.port", "must be in 1..65535, got %d", c.PostgreSQL.Port)
+	v.check(c.PostgreSQL.DB != "", "postgres.db", "is required")
+	v.oneOf("postgres.sslmode", c.PostgreSQL.SSLMode, "disable", "require", "verify-ca", "verify-full")
+	v.notNegative("postgres.statement_timeout", int64(c.PostgreSQL.StatementTimeout))
+	v.notNegative("postgres.pool.max_open_conns", int64(c.PostgreSQL.MaxOpenConns))
+	v.notNegative("postgres.pool.max_idle_conns", int64(c.PostgreSQL.MaxIdleConns))
+	v.notNegative("postgres.pool.conn_max_lifetime", int64(c.PostgreSQL.ConnMaxLifetime))
+	v.notNegative("postgres.pool.conn_max_idle_time", int64(c.PostgreSQL.ConnMaxIdleTime))
+	v.check(len(c.PostgreSQL.Search.Fields) > 0, "postgres.search.fields", "is required")
+	for _, field := range c.PostgreSQL.Search.Fields {
+		v.check(searchFieldRe.MatchString(field), "postgres.search.fields", "%q is not a top-level content key", field)
+	}
+	v.check(c.PostgreSQL.Search.Language != "", "postgres.search.language", "is required")
+
+	v.oneOf("redis.mode", c.Redis.Mode, "standalone", "sentinel", "cluster")
+	v.check(len(c.Redis.Addresses()) > 0, "redis.addr", "addr or addrs is required")
+	if c.Redis.Mode == "sentinel" {
+		v.check(c.Redis.MasterName != "", "redis.master_name", "is required in sentinel mode")
+	}
+	v.notNegative("redis.db", int64(c.Redis.DB))
+	v.check(c.Redis.Protocol == 0 || c.Redis.Protocol == 2 || c.Redis.Protocol == 3, "redis.protocol", "must be 2 or 3, got %d", c.Redis.Protocol)
+	v.notNegative("redis.pool.size", int64(c.Redis.Pool.Size))
+	v.notNegative("redis.pool.min_idle_conns", int64(c.Redis.Pool.MinIdleConns))
+	v.positive("redis.pool.dial_timeout", c.Redis.Pool.DialTimeout)
+	v.check((c.Redis.TLS.CertFile == "") == (c.Redis.TLS.KeyFile == ""), "redis.tls", "cert_file and key_file must be set together")
+	v.positive("redis.cache.banner_ttl", c.Redis.Cache.BannerTTL)
+	v.notNegative("redis.cache.not_found_ttl", int64(c.Redis.Cache.NotFoundTTL))
+	v.check(c.Redis.Cache.KeyVersion > 0, "redis.cache.key_version", "must be positive, got %d", c.Redis.Cache.KeyVersion)
+	v.check(c.Redis.Breaker.FailureThreshold > 0, "redis.breaker.failure_threshold", "must be positive, got %d", c.Redis.Breaker.FailureThreshold)
+	v.positive("redis.breaker.open_timeout", c.Redis.Breaker.OpenTimeout)
+	v.positive("redis.breaker.probe_interval", c.Redis.Breaker.ProbeInterval)
+	v.check(c.Redis.Warmup.BatchSize > 0, "redis.warmup.batch_size", "must be positive, got %d", c.Redis.Warmup.BatchSize)
+
+	v.oneOf("rate_limit.mode", c.RateLimit.Mode, "memory", "redis")
+	v.limit("rate_limit.user_banner", c.RateLimit.UserBanner)
+	v.limit("rate_limit.admin", c.RateLimit.Admin)
+
+	return v
+}
+
+// Validate returns all invalid fields of mock user
+func (u *User) Validate() []error {
+	var v validator
+
+	v.check(u.Username != "", "user.username", "is required")
+	v.oneOf("user.role", u.Role, "admin", "user")
+
+	return v
+}
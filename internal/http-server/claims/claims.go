@@ -0,0 +1,27 @@
+package claims
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// TagID returns tag of user from JWT claims. Numbers of decoded token are float64,
+// claims set in process (e.g. in tests) may keep integer types
+func TagID(claims map[string]interface{}) (int64, bool) {
+	switch tag := claims["tag"].(type) {
+	case float64:
+		if tag != math.Trunc(tag) {
+			return 0, false
+		}
+		return int64(tag), true
+	case int:
+		return int64(tag), true
+	case int64:
+		return tag, true
+	case json.Number:
+		tagID, err := tag.Int64()
+		return tagID, err == nil
+	default:
+		return 0, false
+	}
+}
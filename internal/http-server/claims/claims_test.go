@@ -0,0 +1,36 @@
+package claims
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTagID(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  interface{}
+		want int64
+		ok   bool
+	}{
+		{name: "decoded token", tag: float64(7), want: 7, ok: true},
+		{name: "int", tag: 7, want: 7, ok: true},
+		{name: "int64", tag: int64(7), want: 7, ok: true},
+		{name: "json number", tag: json.Number("7"), want: 7, ok: true},
+		{name: "fraction", tag: 7.5},
+		{name: "string", tag: "7"},
+		{name: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := map[string]interface{}{}
+			if tt.tag != nil {
+				claims["tag"] = tt.tag
+			}
+
+			got, ok := TagID(claims)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("TagID = %d, %t, want %d, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
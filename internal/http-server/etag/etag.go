@@ -0,0 +1,108 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version formats banner version as strong entity tag
+func Version(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// Versions parses If-Match header into acceptable versions,
+// nil means header is absent or "*", so any version is accepted
+func Versions(header string) ([]int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	versions := make([]int64, 0, 1)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// weak tags never match in If-Match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity tag %q", tag)
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// Matches reports whether version is one of versions parsed by Versions,
+// nil versions match any version
+func Matches(version int64, versions []int64) bool {
+	if versions == nil {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NoneMatch reports whether If-None-Match header matches etag, weak comparison is used
+func NoneMatch(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Content formats strong entity tag of banner version and content hash, content is
+// canonicalized, so copies from PostgreSQL and Redis get the same tag
+func Content(version int64, content []byte) string {
+	canonical := content
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err == nil {
+		if data, err := json.Marshal(v); err == nil {
+			canonical = data
+		}
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return fmt.Sprintf(`"%d-%s"`, version, hex.EncodeToString(sum[:8]))
+}
+
+// NotModified reports whether client copy with given validators is still fresh,
+// If-Modified-Since is only used without If-None-Match
+func NotModified(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return NoneMatch(header, etag)
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !modified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
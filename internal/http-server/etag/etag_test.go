@@ -0,0 +1,130 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int64
+		versions []int64
+		want     bool
+	}{
+		{name: "any version", version: 3, versions: nil, want: true},
+		{name: "one of versions", version: 3, versions: []int64{2, 3}, want: true},
+		{name: "not one of versions", version: 4, versions: []int64{2, 3}, want: false},
+		{name: "only weak tags", version: 3, versions: []int64{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.version, tt.versions); got != tt.want {
+				t.Errorf("Matches(%d, %v) = %v, want %v", tt.version, tt.versions, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int64
+	}{
+		{header: "", want: nil},
+		{header: " * ", want: nil},
+		{header: `"3"`, want: []int64{3}},
+		{header: `"3", "5"`, want: []int64{3, 5}},
+		{header: `W/"3", "5"`, want: []int64{5}},
+		{header: `W/"3"`, want: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := Versions(tt.header)
+			if err != nil {
+				t.Fatalf("Versions: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Versions = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionsInvalid(t *testing.T) {
+	for _, header := range []string{`"abc"`, `"3", x`, `"3-abcdef"`} {
+		t.Run(header, func(t *testing.T) {
+			if _, err := Versions(header); err == nil {
+				t.Errorf("Versions error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestContent(t *testing.T) {
+	base := Content(3, []byte(`{"title": "sale", "url": "https://example.com"}`))
+
+	tests := []struct {
+		name    string
+		version int64
+		content string
+		same    bool
+	}{
+		{name: "same content", version: 3, content: `{"title": "sale", "url": "https://example.com"}`, same: true},
+		{name: "other key order and spaces", version: 3, content: `{"url":"https://example.com","title":"sale"}`, same: true},
+		{name: "other version", version: 4, content: `{"title": "sale", "url": "https://example.com"}`, same: false},
+		{name: "other content", version: 3, content: `{"title": "new", "url": "https://example.com"}`, same: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Content(tt.version, []byte(tt.content)); (got == base) != tt.same {
+				t.Errorf("Content = %s, base %s, want same %v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const tag = `"3-0123456789abcdef"`
+	modified := time.Date(2024, 4, 10, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		modified time.Time
+		want     bool
+	}{
+		{name: "no validators", modified: modified, want: false},
+		{name: "matching tag", headers: map[string]string{"If-None-Match": tag}, modified: modified, want: true},
+		{name: "weak matching tag", headers: map[string]string{"If-None-Match": `"1", W/` + tag}, modified: modified, want: true},
+		{name: "any tag", headers: map[string]string{"If-None-Match": "*"}, modified: modified, want: true},
+		{name: "other tag", headers: map[string]string{"If-None-Match": `"2-0123456789abcdef"`}, modified: modified, want: false},
+		{
+			name: "other tag wins over fresh date",
+			headers: map[string]string{
+				"If-None-Match":     `"2-0123456789abcdef"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			modified: modified,
+			want:     false,
+		},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, modified: modified, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, modified: modified, want: false},
+		{name: "unknown modification time", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, want: false},
+		{name: "invalid date", headers: map[string]string{"If-Modified-Since": "yesterday"}, modified: modified, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/user_banner", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if got := NotModified(r, tag, tt.modified); got != tt.want {
+				t.Errorf("NotModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
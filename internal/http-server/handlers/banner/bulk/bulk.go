@@ -0,0 +1,237 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+const maxOperations = 1000
+
+// Operation is create (tag_ids, feature_id, content, is_active), update (id and
+// fields to change, as in PATCH /banner/{id}) or delete (id); if_match is an
+// entity tag as in If-Match header
+type Operation struct {
+	Op        string          `json:"op"`
+	ID        int64           `json:"id"`
+	TagIDs    []int           `json:"tag_ids"`
+	FeatureID int64           `json:"feature_id"`
+	Content   json.RawMessage `json:"content"`
+	IsActive  *bool           `json:"is_active"`
+	IfMatch   string          `json:"if_match"`
+}
+
+type Request struct {
+	Operations []Operation `json:"operations"`
+}
+
+type Result struct {
+	Index    int                 `json:"index"`
+	Op       string              `json:"op"`
+	Status   string              `json:"status"`
+	Code     int                 `json:"code"`
+	BannerID int64               `json:"banner_id,omitempty"`
+	Version  int64               `json:"version,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Details  []schema.FieldError `json:"details,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Atomic  bool     `json:"atomic"`
+	Results []Result `json:"results"`
+}
+
+type BannerBulk interface {
+	BulkBanners(ctx context.Context, ops []postgresql.BannerOp, atomic bool, check postgresql.ContentCheck) ([]postgresql.BannerOpResult, error)
+}
+
+type ContentValidator interface {
+	ValidateContentWith(ctx context.Context, getter schema.SchemaGetter, featureID int64, content []byte) ([]schema.FieldError, error)
+}
+
+// New applies create, update and delete operations on banners. With atomic=true
+// all of them are applied in one transaction or none, the response status is
+// the one of the failed operation; otherwise each is applied on its own and
+// results are reported per operation
+func New(log *slog.Logger, bannerBulk BannerBulk, contentValidator ContentValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.bulk.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		atomic := false
+		if atomicStr := r.URL.Query().Get("atomic"); atomicStr != "" {
+			var err error
+			atomic, err = strconv.ParseBool(atomicStr)
+			if err != nil {
+				log.Error("request query parameter atomic is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil || len(req.Operations) == 0 || len(req.Operations) > maxOperations {
+			log.Error("invalid request body", slog.Int("operations", len(req.Operations)))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		ops := make([]postgresql.BannerOp, len(req.Operations))
+		for i, operation := range req.Operations {
+			ops[i], err = bannerOp(operation)
+			if err != nil {
+				log.Error("invalid operation", slog.Int("index", i), slog.String("error", err.Error()))
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error(fmt.Sprintf("Некорректные данные в операции %d", i)))
+				return
+			}
+		}
+		log.Info("request body decoded", slog.Int("operations", len(ops)), slog.Bool("atomic", atomic))
+
+		check := func(ctx context.Context, schemas schema.SchemaGetter, featureID int64, content []byte) error {
+			violations, err := contentValidator.ValidateContentWith(ctx, schemas, featureID, content)
+			if err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return &schema.ViolationsError{Violations: violations}
+			}
+			return nil
+		}
+
+		opResults, err := bannerBulk.BulkBanners(r.Context(), ops, atomic, check)
+		if err != nil {
+			log.Error("failed to apply operations", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		res := Response{Response: resp.OK(), Atomic: atomic, Results: make([]Result, len(opResults))}
+		status, failed := 200, 0
+		for i, opResult := range opResults {
+			res.Results[i] = result(log, i, ops[i].Op, opResult)
+			if opResult.Status == postgresql.BulkStatusFailed {
+				failed++
+				status = res.Results[i].Code
+			}
+		}
+
+		log.Info("operations applied", slog.Int("count", len(ops)), slog.Int("failed", failed))
+
+		if atomic && failed > 0 {
+			res.Response = resp.Error("Операции отменены")
+			render.Status(r, status)
+			render.JSON(w, r, res)
+			return
+		}
+
+		render.Status(r, 200)
+		render.JSON(w, r, res)
+	}
+}
+
+func bannerOp(operation Operation) (postgresql.BannerOp, error) {
+	ifMatch, err := etag.Versions(operation.IfMatch)
+	if err != nil {
+		return postgresql.BannerOp{}, err
+	}
+
+	// null content is the same as absent one
+	content := operation.Content
+	if bytes.Equal(content, []byte("null")) {
+		content = nil
+	}
+
+	switch operation.Op {
+	case postgresql.BulkCreate:
+	case postgresql.BulkUpdate, postgresql.BulkDelete:
+		if operation.ID <= 0 {
+			return postgresql.BannerOp{}, fmt.Errorf("operation %s requires id", operation.Op)
+		}
+	default:
+		return postgresql.BannerOp{}, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+
+	return postgresql.BannerOp{
+		Op:        operation.Op,
+		BannerID:  operation.ID,
+		FeatureID: operation.FeatureID,
+		TagIDs:    operation.TagIDs,
+		Content:   content,
+		IsActive:  operation.IsActive,
+		IfMatch:   ifMatch,
+	}, nil
+}
+
+// result maps outcome of operation to the status codes of single banner endpoints
+func result(log *slog.Logger, index int, op string, opResult postgresql.BannerOpResult) Result {
+	res := Result{
+		Index:    index,
+		Op:       op,
+		Status:   opResult.Status,
+		BannerID: opResult.BannerID,
+		Version:  opResult.Version,
+	}
+
+	var violations *schema.ViolationsError
+	switch err := opResult.Err; {
+	case opResult.Status == postgresql.BulkStatusRolledBack || opResult.Status == postgresql.BulkStatusSkipped:
+		res.Code = http.StatusFailedDependency
+		res.Version = 0
+	case err == nil && op == postgresql.BulkCreate:
+		res.Code = http.StatusCreated
+	case err == nil && op == postgresql.BulkDelete:
+		res.Code = http.StatusNoContent
+	case err == nil:
+		res.Code = http.StatusOK
+	case errors.As(err, &violations):
+		res.Code, res.Error, res.Details = 400, "Некорректные данные", violations.Violations
+	case errors.Is(err, storage.ErrBannerInvalidData):
+		res.Code, res.Error = 400, "Некорректные данные"
+	case errors.Is(err, storage.ErrBannerNotExists):
+		res.Code, res.Error = 404, "Баннер не найден"
+	case errors.Is(err, storage.ErrBannerExists):
+		res.Code, res.Error = 409, "Баннер уже существует"
+	case errors.Is(err, storage.ErrBannerVersion):
+		res.Code, res.Error = 412, "Баннер был изменен"
+	default:
+		log.Error("failed to apply operation", slog.Int("index", index), slog.String("error", err.Error()))
+		res.Code, res.Error = 500, "Внутренняя ошибка сервера"
+	}
+
+	return res
+}
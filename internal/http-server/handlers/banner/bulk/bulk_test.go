@@ -0,0 +1,105 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+)
+
+func TestResult(t *testing.T) {
+	violations := []schema.FieldError{{Field: "/title", Message: "missing property"}}
+
+	tests := []struct {
+		name     string
+		op       string
+		opResult postgresql.BannerOpResult
+		want     Result
+	}{
+		{
+			name:     "created",
+			op:       postgresql.BulkCreate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusOK, BannerID: 5, Version: 1},
+			want:     Result{Status: postgresql.BulkStatusOK, Code: http.StatusCreated, BannerID: 5, Version: 1},
+		},
+		{
+			name:     "updated",
+			op:       postgresql.BulkUpdate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusOK, BannerID: 5, Version: 2},
+			want:     Result{Status: postgresql.BulkStatusOK, Code: http.StatusOK, BannerID: 5, Version: 2},
+		},
+		{
+			name:     "deleted",
+			op:       postgresql.BulkDelete,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusOK, BannerID: 5},
+			want:     Result{Status: postgresql.BulkStatusOK, Code: http.StatusNoContent, BannerID: 5},
+		},
+		{
+			name:     "rolled back",
+			op:       postgresql.BulkUpdate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusRolledBack, BannerID: 5, Version: 2},
+			want:     Result{Status: postgresql.BulkStatusRolledBack, Code: http.StatusFailedDependency, BannerID: 5},
+		},
+		{
+			name:     "skipped",
+			op:       postgresql.BulkCreate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusSkipped},
+			want:     Result{Status: postgresql.BulkStatusSkipped, Code: http.StatusFailedDependency},
+		},
+		{
+			name: "schema violations",
+			op:   postgresql.BulkCreate,
+			opResult: postgresql.BannerOpResult{
+				Status: postgresql.BulkStatusFailed,
+				Err:    fmt.Errorf("op: %w", &schema.ViolationsError{Violations: violations}),
+			},
+			want: Result{Status: postgresql.BulkStatusFailed, Code: http.StatusBadRequest, Error: "Некорректные данные", Details: violations},
+		},
+		{
+			name:     "invalid data",
+			op:       postgresql.BulkUpdate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusFailed, BannerID: 5, Err: storage.ErrBannerInvalidData},
+			want:     Result{Status: postgresql.BulkStatusFailed, Code: http.StatusBadRequest, BannerID: 5, Error: "Некорректные данные"},
+		},
+		{
+			name:     "not exists",
+			op:       postgresql.BulkDelete,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusFailed, BannerID: 5, Err: storage.ErrBannerNotExists},
+			want:     Result{Status: postgresql.BulkStatusFailed, Code: http.StatusNotFound, BannerID: 5, Error: "Баннер не найден"},
+		},
+		{
+			name:     "exists",
+			op:       postgresql.BulkCreate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusFailed, Err: storage.ErrBannerExists},
+			want:     Result{Status: postgresql.BulkStatusFailed, Code: http.StatusConflict, Error: "Баннер уже существует"},
+		},
+		{
+			name:     "version",
+			op:       postgresql.BulkUpdate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusFailed, BannerID: 5, Err: storage.ErrBannerVersion},
+			want:     Result{Status: postgresql.BulkStatusFailed, Code: http.StatusPreconditionFailed, BannerID: 5, Error: "Баннер был изменен"},
+		},
+		{
+			name:     "internal",
+			op:       postgresql.BulkUpdate,
+			opResult: postgresql.BannerOpResult{Status: postgresql.BulkStatusFailed, BannerID: 5, Err: errors.New("connection reset")},
+			want:     Result{Status: postgresql.BulkStatusFailed, Code: http.StatusInternalServerError, BannerID: 5, Error: "Внутренняя ошибка сервера"},
+		},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Index, tt.want.Op = i, tt.op
+			if got := result(log, i, tt.op, tt.opResult); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("result = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
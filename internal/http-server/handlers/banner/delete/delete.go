@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
 	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
 	"github.com/JustForWorld/banner-shift/internal/storage"
 	"github.com/go-chi/chi/middleware"
@@ -25,10 +26,16 @@ type Response struct {
 }
 
 type BannerRemove interface {
-	DeleteBanner(ctx context.Context, bannerID int64) error
+	DeleteBanner(ctx context.Context, bannerID int64, ifMatch []int64) (int64, error)
 }
 
-func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
+type CacheFlusher interface {
+	FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error)
+}
+
+// New removes banner, with If-Match only if its version still matches;
+// cached banners of its feature are flushed
+func New(log *slog.Logger, bannerRemove BannerRemove, cacheFlusher CacheFlusher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.banner.delete.New"
 
@@ -38,7 +45,6 @@ func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
 		)
 
 		_, claims, _ := jwtauth.FromContext(r.Context())
-		fmt.Println(claims)
 		if claims["role"] != "admin" {
 			render.Status(r, 403)
 			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
@@ -51,8 +57,7 @@ func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
 		)
 		req.ID, err = strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 		if err != nil {
-			log.Error("request path parameter id is not integer")
-			fmt.Println(chi.URLParam(r, "id"))
+			log.Error("request path parameter id is not integer", slog.String("id", chi.URLParam(r, "id")))
 
 			render.Status(r, 400)
 			render.JSON(w, r, resp.Error("Некорректные данные"))
@@ -60,7 +65,23 @@ func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
 		}
 		log.Info("path parameter is valid", slog.Any("request", req))
 
-		err = bannerRemove.DeleteBanner(r.Context(), req.ID)
+		ifMatch, err := etag.Versions(r.Header.Get("If-Match"))
+		if err != nil {
+			log.Error("invalid If-Match header", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		featureID, err := bannerRemove.DeleteBanner(r.Context(), req.ID, ifMatch)
+		if errors.Is(err, storage.ErrBannerVersion) {
+			log.Info("banner version mismatch", slog.Int64("id", req.ID), slog.Any("if_match", ifMatch))
+
+			render.Status(r, 412)
+			render.JSON(w, r, resp.Error("Баннер был изменен"))
+			return
+		}
 		if errors.Is(err, storage.ErrBannerNotExists) {
 			log.Info("banner not found",
 				slog.Any("banner_id", req.ID),
@@ -72,7 +93,7 @@ func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
 			return
 		}
 		if err != nil {
-			log.Error("failed to create banner", err)
+			log.Error("failed to delete banner", slog.String("error", err.Error()))
 
 			render.Status(r, 500)
 			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
@@ -81,6 +102,14 @@ func New(log *slog.Logger, bannerRemove BannerRemove) http.HandlerFunc {
 
 		log.Info("banner removed", slog.Any("id", req.ID))
 
+		// banner may be cached for descendant tags as well, cache is best effort
+		if _, err := cacheFlusher.FlushBanners(r.Context(), 0, featureID); err != nil {
+			log.Warn("failed to flush cached banners of feature",
+				slog.Int64("feature_id", featureID),
+				slog.String("error", err.Error()),
+			)
+		}
+
 		fmt.Fprintln(w, http.StatusNoContent)
 	}
 }
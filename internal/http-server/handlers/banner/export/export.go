@@ -0,0 +1,96 @@
+package export
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/JustForWorld/banner-shift/internal/transfer"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// flushEvery is number of banners written between flushes of response
+const flushEvery = 100
+
+type BannerExporter interface {
+	ExportBanners(ctx context.Context, fn func(*postgresql.Banner) error) error
+}
+
+// New streams all banners in format jsonl (default), csv or yaml
+func New(log *slog.Logger, bannerExporter BannerExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.export.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = transfer.FormatJSONL
+		}
+		writer, err := transfer.NewWriter(w, format)
+		if err != nil {
+			log.Error("invalid request query", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		w.Header().Set("Content-Type", transfer.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="banners.`+format+`"`)
+		w.WriteHeader(http.StatusOK)
+
+		flusher, _ := w.(http.Flusher)
+		count := 0
+		err = bannerExporter.ExportBanners(r.Context(), func(banner *postgresql.Banner) error {
+			err := writer.Write(transfer.Record{
+				BannerID:  banner.BannerID,
+				FeatureID: banner.FeatureID,
+				TagIDs:    banner.TagIDs,
+				IsActive:  banner.IsActive,
+				Content:   banner.Content,
+				CreatedAt: banner.CreatedAT,
+				UpdatedAt: banner.UpdatedAT,
+				Version:   banner.Version,
+			})
+			if err != nil {
+				return err
+			}
+
+			count++
+			if count%flushEvery == 0 {
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+		// status is already sent, so failure just truncates the stream
+		if err != nil {
+			log.Error("failed to export banners", slog.Int("exported", count), slog.String("error", err.Error()))
+			return
+		}
+
+		log.Info("banners exported", slog.String("format", format), slog.Int("count", count))
+	}
+}
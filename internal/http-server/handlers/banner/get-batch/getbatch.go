@@ -0,0 +1,218 @@
+package getbatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	jwtclaims "github.com/JustForWorld/banner-shift/internal/http-server/claims"
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// maxFeatures limits features requested at once
+const maxFeatures = 100
+
+const (
+	ItemStatusOK       = "OK"
+	ItemStatusNotFound = "not_found"
+)
+
+type Request struct {
+	TagID           int64   `json:"tag_id"`
+	FeatureIDs      []int64 `json:"feature_ids"`
+	UseLastRevision bool    `json:"use_last_revision"`
+}
+
+type Item struct {
+	FeatureID    int64           `json:"feature_id"`
+	Status       string          `json:"status"`
+	Content      json.RawMessage `json:"content,omitempty"`
+	MatchedTagID int64           `json:"matched_tag_id,omitempty"`
+	Fallback     bool            `json:"fallback,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	TagID   int64  `json:"tag_id"`
+	Banners []Item `json:"banners"`
+}
+
+type BannerGetter interface {
+	GetTagBanners(ctx context.Context, tagID int64, featureIDs []int64) (map[int64]*storage.ResolvedBanner, error)
+}
+
+type BannerGetterCache interface {
+	GetTagBanners(ctx context.Context, tagID int64, featureIDs []int64) (map[int64]*storage.ResolvedBanner, error)
+	SetTagBanners(ctx context.Context, tagID int64, banners map[int64]*storage.ResolvedBanner) error
+}
+
+// New returns banners of several features for one tag: POST with JSON body
+// or GET with tag_id and repeated feature_id query parameters
+func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGetterCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.getBatch.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req, err := decodeRequest(r)
+		if err != nil {
+			log.Error("invalid request", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		log.Info("request is valid", slog.Any("request", req))
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] == "user" {
+			userTag, ok := jwtclaims.TagID(claims)
+			if ok && userTag != req.TagID {
+				render.Status(r, 404)
+				render.JSON(w, r, resp.Error(fmt.Sprintf("Баннер для %v не найден", claims["username"])))
+				return
+			}
+		}
+
+		banners := make(map[int64]*storage.ResolvedBanner, len(req.FeatureIDs))
+		misses := req.FeatureIDs
+
+		// cached banners, nil value is a cached "not found"
+		if !req.UseLastRevision {
+			cached, err := bannerGetterCache.GetTagBanners(r.Context(), req.TagID, req.FeatureIDs)
+			if err != nil {
+				log.Info("banners not found in Redis", slog.String("error", err.Error()))
+			}
+
+			misses = make([]int64, 0, len(req.FeatureIDs))
+			for _, featureID := range req.FeatureIDs {
+				banner, ok := cached[featureID]
+				if !ok {
+					misses = append(misses, featureID)
+					continue
+				}
+				banners[featureID] = banner
+			}
+		}
+
+		if len(misses) > 0 {
+			found, err := bannerGetter.GetTagBanners(r.Context(), req.TagID, misses)
+			if err != nil {
+				log.Error("failed to get banners", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+
+			// populate Redis with found and missing banners
+			fresh := make(map[int64]*storage.ResolvedBanner, len(misses))
+			for _, featureID := range misses {
+				fresh[featureID] = found[featureID]
+				banners[featureID] = found[featureID]
+			}
+			if err := bannerGetterCache.SetTagBanners(r.Context(), req.TagID, fresh); err != nil {
+				log.Warn("failed to cache banners", slog.String("error", err.Error()))
+			}
+		}
+
+		res := Response{
+			Response: resp.OK(),
+			TagID:    req.TagID,
+			Banners:  make([]Item, 0, len(req.FeatureIDs)),
+		}
+		for _, featureID := range req.FeatureIDs {
+			banner := banners[featureID]
+			if banner == nil {
+				res.Banners = append(res.Banners, Item{FeatureID: featureID, Status: ItemStatusNotFound})
+				continue
+			}
+			res.Banners = append(res.Banners, Item{
+				FeatureID:    featureID,
+				Status:       ItemStatusOK,
+				Content:      banner.Content,
+				MatchedTagID: banner.TagID,
+				Fallback:     banner.Fallback,
+			})
+		}
+
+		log.Info("banners found", slog.Int("requested", len(req.FeatureIDs)), slog.Int("found", countFound(banners)))
+
+		render.Status(r, 200)
+		render.JSON(w, r, res)
+	}
+}
+
+func decodeRequest(r *http.Request) (Request, error) {
+	var req Request
+
+	if r.Method == http.MethodPost {
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			return req, fmt.Errorf("request body is empty")
+		}
+		if err != nil {
+			return req, fmt.Errorf("failed to decode request body: %w", err)
+		}
+	} else {
+		query := r.URL.Query()
+
+		var err error
+		req.TagID, err = strconv.ParseInt(query.Get("tag_id"), 10, 64)
+		if err != nil {
+			return req, fmt.Errorf("request query parameter tag_id is not integer")
+		}
+		for _, featureIDStr := range query["feature_id"] {
+			featureID, err := strconv.ParseInt(featureIDStr, 10, 64)
+			if err != nil {
+				return req, fmt.Errorf("request query parameter feature_id is not integer")
+			}
+			req.FeatureIDs = append(req.FeatureIDs, featureID)
+		}
+		req.UseLastRevision = query.Get("use_last_revision") == "true"
+	}
+
+	if req.TagID <= 0 || len(req.FeatureIDs) == 0 || len(req.FeatureIDs) > maxFeatures {
+		return req, fmt.Errorf("tag_id and 1..%d feature_ids are required", maxFeatures)
+	}
+
+	// drop duplicates keeping the order
+	seen := make(map[int64]bool, len(req.FeatureIDs))
+	featureIDs := req.FeatureIDs[:0]
+	for _, featureID := range req.FeatureIDs {
+		if featureID <= 0 {
+			return req, fmt.Errorf("feature_id must be positive")
+		}
+		if seen[featureID] {
+			continue
+		}
+		seen[featureID] = true
+		featureIDs = append(featureIDs, featureID)
+	}
+	req.FeatureIDs = featureIDs
+
+	return req, nil
+}
+
+func countFound(banners map[int64]*storage.ResolvedBanner) int {
+	found := 0
+	for _, banner := range banners {
+		if banner != nil {
+			found++
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,87 @@
+package getbyid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	*postgresql.Banner
+}
+
+type BannerGetter interface {
+	GetBannerByID(ctx context.Context, bannerID int64) (*postgresql.Banner, error)
+}
+
+// New returns banner with its version in ETag, which PATCH and DELETE accept
+// in If-Match; unchanged banner is answered with 304 on If-None-Match
+func New(log *slog.Logger, bannerGetter BannerGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.getByID.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		bannerID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		banner, err := bannerGetter.GetBannerByID(r.Context(), bannerID)
+		if errors.Is(err, storage.ErrBannerNotExists) {
+			log.Info("banner not found", slog.Int64("id", bannerID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Баннер не найден"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get banner", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		tag := etag.Version(banner.Version)
+		w.Header().Set("ETag", tag)
+		if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etag.NoneMatch(ifNoneMatch, tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		log.Info("banner found", slog.Int64("id", bannerID), slog.Int64("version", banner.Version))
+
+		render.Status(r, 200)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Banner:   banner,
+		})
+	}
+}
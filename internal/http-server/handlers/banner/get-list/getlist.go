@@ -2,40 +2,45 @@ package getlist
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	response "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
 	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
 )
 
-type Request struct {
-	FeatureID int64 `json:"feature_id"`
-	TagID     int64 `json:"tag_id"`
-	Limit     int64 `json:"limit"`
-	Offset    int64 `json:"offset"`
-}
-
 type Response struct {
 	response.Response
-	Banners []*postgresql.Banner
+	Total      int64                `json:"total"`
+	Banners    []*postgresql.Banner `json:"banners"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	PrevCursor string               `json:"prev_cursor,omitempty"`
 }
 
 type BannerGetterList interface {
-	GetBannerList(ctx context.Context, featureID, tagID, limit, offset int64) ([]*postgresql.Banner, error)
+	GetBannerList(ctx context.Context, filter postgresql.BannerFilter) (*postgresql.BannerPage, error)
 }
 
-func New(log *slog.Logger, bannerGetterList BannerGetterList) http.HandlerFunc {
+// New returns banners filtered by feature_id, tag_id (repeated or comma separated),
+// is_active, created_from/created_to, updated_from/updated_to (RFC 3339) and
+// sorted by sort_by (id, created_at, updated_at) in order (asc, desc); pages are
+// selected with cursor (next_cursor/prev_cursor of previous response) or offset,
+// limit defaults to defaultLimit and is capped by maxLimit
+func New(log *slog.Logger, bannerGetterList BannerGetterList, defaultLimit, maxLimit int64) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.banner.getList.New"
 
 		_, claims, _ := jwtauth.FromContext(r.Context())
-		fmt.Println(claims)
 		if claims["role"] != "admin" {
 			render.Status(r, 403)
 			render.JSON(w, r, response.Error("Пользователь не имеет доступа"))
@@ -47,76 +52,135 @@ func New(log *slog.Logger, bannerGetterList BannerGetterList) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		var (
-			req Request
-			err error
-		)
-
-		featureIDStr := r.URL.Query().Get("feature_id")
-		if featureIDStr != "" {
-			req.FeatureID, err = strconv.ParseInt(featureIDStr, 10, 64)
-			if err != nil {
-				log.Error("request query parameter feature_id is not integer")
-				fmt.Printf("%T %v\n", req.FeatureID, req.FeatureID)
+		filter, err := parseFilter(r.URL.Query(), defaultLimit, maxLimit)
+		if err != nil {
+			log.Error("invalid request query", slog.String("error", err.Error()))
 
-				render.Status(r, 400)
-				render.JSON(w, r, response.Error("Некорректные данные"))
-				return
-			}
+			render.Status(r, 400)
+			render.JSON(w, r, response.Error("Некорректные данные"))
+			return
 		}
+		log.Info("request query parameter is valid", slog.Any("filter", filter))
 
-		tagIDStr := r.URL.Query().Get("tag_id")
-		if tagIDStr != "" {
-			req.TagID, err = strconv.ParseInt(tagIDStr, 10, 64)
-			if err != nil {
-				log.Error("request query parameter tag_id is not integer")
-				fmt.Printf("%T %v\n", req.TagID, req.TagID)
+		page, err := bannerGetterList.GetBannerList(r.Context(), filter)
+		if errors.Is(err, storage.ErrBannerInvalidData) {
+			log.Error("invalid banner list filter", slog.String("error", err.Error()))
 
-				render.Status(r, 400)
-				render.JSON(w, r, response.Error("Некорректные данные"))
-				return
-			}
+			render.Status(r, 400)
+			render.JSON(w, r, response.Error("Некорректные данные"))
+			return
 		}
-		limitStr := r.URL.Query().Get("limit")
-		if limitStr != "" {
-			req.Limit, err = strconv.ParseInt(limitStr, 10, 64)
-			fmt.Printf("%T %v\n", req.Limit, req.Limit)
-			if err != nil {
-				log.Error("request query parameter limit is not integer")
-				fmt.Printf("%T %v\n", req.Limit, req.Limit)
+		if err != nil {
+			log.Error("failed to get banner list", slog.String("error", err.Error()))
 
-				render.Status(r, 400)
-				render.JSON(w, r, response.Error("Некорректные данные"))
-				return
-			}
+			render.Status(r, 500)
+			render.JSON(w, r, response.Error("Внутренняя ошибка сервера"))
+			return
 		}
-		offsetStr := r.URL.Query().Get("offset")
-		if offsetStr != "" {
-			req.Offset, err = strconv.ParseInt(offsetStr, 10, 64)
-			if err != nil {
-				log.Error("request query parameter offset is not integer")
-				fmt.Printf("%T %v\n", req.Offset, req.Offset)
 
-				render.Status(r, 400)
-				render.JSON(w, r, response.Error("Некорректные данные"))
-				return
-			}
+		log.Info("banners found", slog.Int("count", len(page.Banners)), slog.Int64("total", page.Total))
+
+		render.Status(r, 200)
+		render.JSON(w, r, Response{
+			Response:   response.OK(),
+			Total:      page.Total,
+			Banners:    page.Banners,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+		})
+	}
+}
+
+func parseFilter(query url.Values, defaultLimit, maxLimit int64) (postgresql.BannerFilter, error) {
+	var (
+		filter postgresql.BannerFilter
+		err    error
+	)
+
+	if filter.FeatureIDs, err = parseIDs(query, "feature_id"); err != nil {
+		return filter, err
+	}
+	if filter.TagIDs, err = parseIDs(query, "tag_id"); err != nil {
+		return filter, err
+	}
+
+	if isActiveStr := query.Get("is_active"); isActiveStr != "" {
+		isActive, err := strconv.ParseBool(isActiveStr)
+		if err != nil {
+			return filter, fmt.Errorf("request query parameter is_active is not boolean")
 		}
+		filter.IsActive = &isActive
+	}
 
-		var resp Response
-		resp.Banners, err = bannerGetterList.GetBannerList(r.Context(), req.FeatureID, req.TagID, req.Limit, req.Offset)
+	for name, dst := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"updated_from": &filter.UpdatedFrom,
+		"updated_to":   &filter.UpdatedTo,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
 		if err != nil {
-			// TODO!!! server error
-			fmt.Println(err)
-			log.Error("server error")
+			return filter, fmt.Errorf("request query parameter %s is not RFC 3339 time", name)
+		}
+		*dst = &t
+	}
 
-			render.Status(r, 500)
-			render.JSON(w, r, response.Error("Некорректные данные"))
-			return
+	filter.SortBy = query.Get("sort_by")
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, fmt.Errorf("request query parameter order must be asc or desc")
+	}
+
+	filter.Limit = defaultLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		filter.Limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || filter.Limit <= 0 {
+			return filter, fmt.Errorf("request query parameter limit is not positive integer")
 		}
+	}
+	if maxLimit > 0 && (filter.Limit > maxLimit || filter.Limit == 0) {
+		filter.Limit = maxLimit
+	}
 
-		log.Info("banners found", slog.Any("banners", resp.Banners))
-		render.Status(r, 200)
-		render.JSON(w, r, resp.Banners)
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.Cursor, err = postgresql.DecodeBannerCursor(cursor)
+		if err != nil {
+			return filter, fmt.Errorf("request query parameter cursor is invalid")
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		filter.Offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || filter.Offset < 0 {
+			return filter, fmt.Errorf("request query parameter offset is not integer")
+		}
+	}
+
+	return filter, nil
+}
+
+// parseIDs reads ids from repeated and comma separated parameter
+func parseIDs(query url.Values, name string) ([]int64, error) {
+	var ids []int64
+
+	for _, value := range query[name] {
+		for _, idStr := range strings.Split(value, ",") {
+			if idStr == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("request query parameter %s is not integer", name)
+			}
+			ids = append(ids, id)
+		}
 	}
+
+	return ids, nil
 }
@@ -8,7 +8,10 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	jwtclaims "github.com/JustForWorld/banner-shift/internal/http-server/claims"
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
 	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
 	"github.com/JustForWorld/banner-shift/internal/storage"
 	"github.com/go-chi/chi/middleware"
@@ -26,14 +29,28 @@ type Response struct {
 	Content json.RawMessage `json:"content"`
 }
 
+const (
+	// HeaderMatchedTag is the tag banner matched: requested one or its ancestor
+	HeaderMatchedTag = "X-Banner-Tag-ID"
+	// HeaderFallback is set when default banner of the feature is served
+	HeaderFallback = "X-Banner-Fallback"
+)
+
 type BannerGetter interface {
-	GetBanner(ctx context.Context, tagID, featureID int64) (json.RawMessage, error)
+	GetBanner(ctx context.Context, tagID, featureID int64) (*storage.ResolvedBanner, error)
 }
 
 type BannerGetterCache interface {
-	GetBanner(ctx context.Context, key string) ([]byte, error)
+	GetBanner(ctx context.Context, tagID, featureID int64) (*storage.ResolvedBanner, error)
+	SetBanner(ctx context.Context, tagID, featureID int64, banner *storage.ResolvedBanner) error
+	SetBannerNotFound(ctx context.Context, tagID, featureID int64) error
+	BannerTTL() time.Duration
 }
 
+// New returns banner content for tag and feature, read through Redis unless
+// use_last_revision is set; responses carry ETag and Last-Modified and are
+// answered with 304 on matching If-None-Match or If-Modified-Since,
+// max-age of Cache-Control is TTL of cached banners
 func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGetterCache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.banner.get.New"
@@ -44,7 +61,7 @@ func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGe
 		)
 
 		_, claims, _ := jwtauth.FromContext(r.Context())
-		log.Debug("user auth", claims["username"], claims["tag"])
+		log.Debug("user auth", slog.Any("username", claims["username"]), slog.Any("tag", claims["tag"]))
 		if claims["role"] == "user" {
 			TagID, err := strconv.ParseInt(r.URL.Query().Get("tag_id"), 10, 64)
 			if err != nil {
@@ -56,8 +73,8 @@ func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGe
 			}
 			log.Info("request query parameter is valid")
 
-			userTag, ok := claims["tag"].(int)
-			if ok && userTag != int(TagID) {
+			userTag, ok := jwtclaims.TagID(claims)
+			if ok && userTag != TagID {
 				render.Status(r, 404)
 				render.JSON(w, r, resp.Error(fmt.Sprintf("Баннер для %v не найден", claims["username"])))
 				return
@@ -87,27 +104,42 @@ func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGe
 		}
 		log.Info("request query parameter is valid", slog.Any("request", req))
 
-		var res Response
 		// check if false get from Redis
 		LastRevision := r.URL.Query().Get("use_last_revision")
+		cacheControl := fmt.Sprintf("private, max-age=%d", int64(bannerGetterCache.BannerTTL().Seconds()))
+		if LastRevision == "true" {
+			cacheControl = "no-cache"
+		}
 		if (LastRevision == "false" || LastRevision == "") && LastRevision != "true" {
-			value, err := bannerGetterCache.GetBanner(r.Context(), fmt.Sprintf("%v:%v", req.TagID, req.FeatureID))
-			if errors.Is(err, storage.ErrBannerNotFound) {
+			banner, err := bannerGetterCache.GetBanner(r.Context(), req.TagID, req.FeatureID)
+			if errors.Is(err, storage.ErrCacheMiss) {
 				log.Info("banner not found in Redis",
 					slog.Any("feature_id", req.FeatureID),
 					slog.Any("tag_id", req.TagID),
 				)
 			}
+			if errors.Is(err, storage.ErrBannerNotFound) {
+				log.Info("banner not found (cached)",
+					slog.Any("feature_id", req.FeatureID),
+					slog.Any("tag_id", req.TagID),
+				)
+
+				render.Status(r, 404)
+				render.JSON(w, r, resp.Error(fmt.Sprintf("Баннер для %v не найден", claims["username"])))
+				return
+			}
 			if err == nil {
-				log.Info("banner found", slog.Any("content", res.Content))
+				log.Info("banner found in Redis",
+					slog.Int64("matched_tag_id", banner.TagID),
+					slog.Any("content", banner.Content),
+				)
 
-				render.Status(r, 200)
-				render.JSON(w, r, string(value))
+				writeBanner(w, r, banner, cacheControl)
 				return
 			}
 		}
 
-		res.Content, err = bannerGetter.GetBanner(r.Context(), req.TagID, req.FeatureID)
+		banner, err := bannerGetter.GetBanner(r.Context(), req.TagID, req.FeatureID)
 		if errors.Is(err, storage.ErrBannerInvalidData) {
 			log.Info("banner with invalid fata",
 				slog.Any("feature_id", req.FeatureID),
@@ -124,21 +156,63 @@ func New(log *slog.Logger, bannerGetter BannerGetter, bannerGetterCache BannerGe
 				slog.Any("tag_id", req.TagID),
 			)
 
+			// negative caching, so repeated misses don't reach PostgreSQL
+			if err := bannerGetterCache.SetBannerNotFound(r.Context(), req.TagID, req.FeatureID); err != nil {
+				log.Warn("failed to cache missing banner", slog.String("error", err.Error()))
+			}
+
 			render.Status(r, 404)
 			render.JSON(w, r, resp.Error(fmt.Sprintf("Баннер для %v не найден", claims["username"])))
 			return
 		}
 		if err != nil {
-			log.Error("failed to create banner", err)
+			log.Error("failed to get banner", slog.String("error", err.Error()))
 
 			render.Status(r, 500)
 			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
 			return
 		}
 
-		log.Info("banner found", slog.Any("content", res.Content))
+		log.Info("banner found",
+			slog.Int64("matched_tag_id", banner.TagID),
+			slog.Bool("fallback", banner.Fallback),
+			slog.Any("content", banner.Content),
+		)
+
+		// populate Redis on cache miss (read-through)
+		if err := bannerGetterCache.SetBanner(r.Context(), req.TagID, req.FeatureID, banner); err != nil {
+			log.Warn("failed to cache banner", slog.String("error", err.Error()))
+		}
+
+		writeBanner(w, r, banner, cacheControl)
+	}
+}
+
+// writeBanner sends banner content or 304 if client copy is still valid
+func writeBanner(w http.ResponseWriter, r *http.Request, banner *storage.ResolvedBanner, cacheControl string) {
+	setBannerHeaders(w, banner)
 
-		render.Status(r, 200)
-		render.JSON(w, r, res.Content)
+	tag := etag.Content(banner.Version, banner.Content)
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", cacheControl)
+	if !banner.UpdatedAt.IsZero() {
+		w.Header().Set("Last-Modified", banner.UpdatedAt.UTC().Format(http.TimeFormat))
 	}
+
+	if etag.NotModified(r, tag, banner.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	render.Status(r, 200)
+	render.JSON(w, r, banner.Content)
+}
+
+func setBannerHeaders(w http.ResponseWriter, banner *storage.ResolvedBanner) {
+	if banner.Fallback {
+		w.Header().Set(HeaderFallback, "true")
+		return
+	}
+
+	w.Header().Set(HeaderMatchedTag, strconv.FormatInt(banner.TagID, 10))
 }
@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/JustForWorld/banner-shift/internal/transfer"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Item struct {
+	Line     int                 `json:"line"`
+	BannerID int64               `json:"banner_id,omitempty"`
+	Action   string              `json:"action"`
+	Error    string              `json:"error,omitempty"`
+	Details  []schema.FieldError `json:"details,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	DryRun    bool   `json:"dry_run"`
+	Policy    string `json:"on_conflict"`
+	MatchByID bool   `json:"match_by_id"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	Aborted   bool   `json:"aborted"`
+	Items     []Item `json:"items"`
+}
+
+type BannerImporter interface {
+	ImportBanners(ctx context.Context, next func() (int, *postgresql.Banner, error), opts postgresql.ImportOptions) (*postgresql.ImportReport, error)
+}
+
+type ContentValidator interface {
+	ValidateContentWith(ctx context.Context, getter schema.SchemaGetter, featureID int64, content []byte) ([]schema.FieldError, error)
+}
+
+// New imports banners in format jsonl (default), csv or yaml as exported by
+// GET /banner/export. Record conflicts with existing banner holding any of its tag
+// and feature pairs, with match_by_id=true with banner of the same banner_id.
+// on_conflict is skip (default), overwrite or fail, with fail the first failed
+// record cancels the whole import; dry_run=true reports changes without applying them
+func New(log *slog.Logger, bannerImporter BannerImporter, contentValidator ContentValidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.import.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = transfer.FormatJSONL
+		}
+		opts := postgresql.ImportOptions{Policy: query.Get("on_conflict")}
+		if opts.Policy == "" {
+			opts.Policy = postgresql.ConflictSkip
+		}
+		if dryRun := query.Get("dry_run"); dryRun != "" {
+			var err error
+			if opts.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+				log.Error("request query parameter dry_run is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+		if matchByID := query.Get("match_by_id"); matchByID != "" {
+			var err error
+			if opts.MatchByID, err = strconv.ParseBool(matchByID); err != nil {
+				log.Error("request query parameter match_by_id is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		reader, err := transfer.NewReader(r.Body, format)
+		if err != nil {
+			log.Error("invalid request query", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		opts.Check = func(ctx context.Context, schemas schema.SchemaGetter, featureID int64, content []byte) error {
+			violations, err := contentValidator.ValidateContentWith(ctx, schemas, featureID, content)
+			if err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return &schema.ViolationsError{Violations: violations}
+			}
+			return nil
+		}
+		next := func() (int, *postgresql.Banner, error) {
+			rec, err := reader.Read()
+			if err != nil {
+				return reader.Line(), nil, err
+			}
+			return reader.Line(), &postgresql.Banner{
+				BannerID:  rec.BannerID,
+				FeatureID: rec.FeatureID,
+				TagIDs:    rec.TagIDs,
+				IsActive:  rec.IsActive,
+				Content:   rec.Content,
+			}, nil
+		}
+
+		report, err := bannerImporter.ImportBanners(r.Context(), next, opts)
+		if errors.Is(err, storage.ErrBannerInvalidData) {
+			log.Error("invalid import", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные: "+err.Error()))
+			return
+		}
+		if err != nil {
+			log.Error("failed to import banners", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		res := Response{
+			Response:  resp.OK(),
+			DryRun:    opts.DryRun,
+			Policy:    opts.Policy,
+			MatchByID: opts.MatchByID,
+			Created:   report.Created,
+			Updated:   report.Updated,
+			Skipped:   report.Skipped,
+			Failed:    report.Failed,
+			Aborted:   report.Aborted,
+			Items:     make([]Item, len(report.Items)),
+		}
+		for i, item := range report.Items {
+			res.Items[i] = Item{Line: item.Line, BannerID: item.BannerID, Action: item.Action}
+			if item.Err != nil {
+				res.Items[i].Error, res.Items[i].Details = itemError(log, item.Err)
+			}
+		}
+
+		log.Info("banners imported",
+			slog.String("format", format),
+			slog.Bool("dry_run", opts.DryRun),
+			slog.Bool("match_by_id", opts.MatchByID),
+			slog.Int("created", report.Created),
+			slog.Int("updated", report.Updated),
+			slog.Int("skipped", report.Skipped),
+			slog.Int("failed", report.Failed),
+		)
+
+		if report.Aborted {
+			res.Response = resp.Error("Импорт отменен")
+			render.Status(r, 409)
+			render.JSON(w, r, res)
+			return
+		}
+
+		render.Status(r, 200)
+		render.JSON(w, r, res)
+	}
+}
+
+func itemError(log *slog.Logger, err error) (string, []schema.FieldError) {
+	var violations *schema.ViolationsError
+	switch {
+	case errors.As(err, &violations):
+		return "Некорректные данные", violations.Violations
+	case errors.Is(err, storage.ErrBannerInvalidData):
+		return "Некорректные данные", nil
+	case errors.Is(err, storage.ErrBannerExists):
+		return "Баннер уже существует", nil
+	default:
+		log.Error("failed to import banner", slog.String("error", err.Error()))
+		return "Внутренняя ошибка сервера", nil
+	}
+}
@@ -1,17 +1,21 @@
 package save
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
 	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
 	"github.com/JustForWorld/banner-shift/internal/storage"
-	"github.com/JustForWorld/banner-shift/internal/storage/redis"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
@@ -30,10 +34,36 @@ type Response struct {
 }
 
 type BannerSaver interface {
-	CreateBanner(ctx context.Context, redis *redis.Storage, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, error)
+	CreateBanner(ctx context.Context, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, time.Time, error)
 }
 
-func New(log *slog.Logger, bannerSaver BannerSaver, redis *redis.Storage) http.HandlerFunc {
+type BannerSaverCache interface {
+	SetBanner(ctx context.Context, tagID, featureID int64, banner *storage.ResolvedBanner) error
+	FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error)
+}
+
+type ContentValidator interface {
+	ValidateContent(ctx context.Context, featureID int64, content []byte) ([]schema.FieldError, error)
+}
+
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (string, *storage.IdempotentResponse, error)
+	CreateBannerIdempotent(ctx context.Context, key, owner string, featureID int64, tagIDs []int, content []byte, isActive bool, respond func(bannerID int64) (*storage.IdempotentResponse, error)) (int64, time.Time, error)
+	ReleaseIdempotencyKey(ctx context.Context, key, owner string) error
+}
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set when stored response is returned
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+)
+
+// New creates banner, content is validated against JSON Schema of feature if it has one.
+// With Idempotency-Key repeated request gets original response for idempotencyTTL,
+// the same key with another body is refused with 422
+func New(log *slog.Logger, bannerSaver BannerSaver, bannerSaverCache BannerSaverCache, contentValidator ContentValidator, idempotencyStore IdempotencyStore, idempotencyTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.banner.save.New"
 
@@ -43,15 +73,23 @@ func New(log *slog.Logger, bannerSaver BannerSaver, redis *redis.Storage) http.H
 		)
 
 		_, claims, _ := jwtauth.FromContext(r.Context())
-		fmt.Println(claims)
 		if claims["role"] != "admin" {
 			render.Status(r, 403)
 			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
 			return
 		}
 
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Error("failed to read request body", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
 		var req Request
-		err := render.DecodeJSON(r.Body, &req)
+		err = render.DecodeJSON(bytes.NewReader(body), &req)
 		// checking for an empty request body
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
@@ -62,7 +100,7 @@ func New(log *slog.Logger, bannerSaver BannerSaver, redis *redis.Storage) http.H
 		}
 
 		if err != nil {
-			log.Error("failed to decode request body", err)
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
 
 			render.Status(r, 400)
 			render.JSON(w, r, resp.Error("Некорректные данные"))
@@ -70,8 +108,111 @@ func New(log *slog.Logger, bannerSaver BannerSaver, redis *redis.Storage) http.H
 		}
 		log.Info("request body decoded", slog.Any("request", req))
 
-		var res Response
-		res.BannerID, err = bannerSaver.CreateBanner(r.Context(), redis, req.FeatureID, req.TagIDs, req.Content, req.IsActive)
+		// key is scoped by user, so clients can't replay responses of each other
+		key := r.Header.Get(HeaderIdempotencyKey)
+		var (
+			owner     string
+			completed bool
+		)
+		if key != "" {
+			if len(key) > maxIdempotencyKeyLen {
+				log.Error("idempotency key is too long")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+			key = fmt.Sprintf("%v:%s", claims["username"], key)
+
+			sum := sha256.Sum256(body)
+			var stored *storage.IdempotentResponse
+			owner, stored, err = idempotencyStore.ReserveIdempotencyKey(r.Context(), key, hex.EncodeToString(sum[:]), idempotencyTTL)
+			if errors.Is(err, storage.ErrIdempotencyMismatch) {
+				log.Info("idempotency key is reused with another request")
+
+				render.Status(r, 422)
+				render.JSON(w, r, resp.Error("Ключ идемпотентности использован с другим запросом"))
+				return
+			}
+			if errors.Is(err, storage.ErrIdempotencyInProgress) {
+				log.Info("request with idempotency key is in progress")
+
+				render.Status(r, 409)
+				render.JSON(w, r, resp.Error("Запрос с этим ключом идемпотентности уже выполняется"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+			if stored != nil {
+				log.Info("idempotent response replayed", slog.Int("status", stored.Status))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(HeaderIdempotentReplayed, "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			// failed request releases key, so it can be retried
+			defer func() {
+				if completed {
+					return
+				}
+				if err := idempotencyStore.ReleaseIdempotencyKey(context.WithoutCancel(r.Context()), key, owner); err != nil {
+					log.Warn("failed to release idempotency key", slog.String("error", err.Error()))
+				}
+			}()
+		}
+
+		if req.Content != nil {
+			violations, err := contentValidator.ValidateContent(r.Context(), req.FeatureID, req.Content)
+			if err != nil {
+				log.Error("failed to validate content", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+			if len(violations) > 0 {
+				log.Info("content does not match feature schema",
+					slog.Int64("feature_id", req.FeatureID),
+					slog.Any("violations", violations),
+				)
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.ValidationError("Некорректные данные", violations))
+				return
+			}
+		}
+
+		var (
+			res       Response
+			respBody  []byte
+			createdAt time.Time
+		)
+		respond := func(bannerID int64) (*storage.IdempotentResponse, error) {
+			body, err := json.Marshal(Response{Response: resp.OK(), BannerID: bannerID})
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode response: %w", err)
+			}
+			respBody = body
+
+			return &storage.IdempotentResponse{Status: http.StatusCreated, Body: body}, nil
+		}
+		if key != "" {
+			// response is stored in transaction of banner, which is finished even if client is gone
+			res.BannerID, createdAt, err = idempotencyStore.CreateBannerIdempotent(context.WithoutCancel(r.Context()), key, owner, req.FeatureID, req.TagIDs, req.Content, req.IsActive, respond)
+		} else {
+			res.BannerID, createdAt, err = bannerSaver.CreateBanner(r.Context(), req.FeatureID, req.TagIDs, req.Content, req.IsActive)
+			if err == nil {
+				_, err = respond(res.BannerID)
+			}
+		}
 		if errors.Is(err, storage.ErrBannerInvalidData) {
 			log.Warn("banner with invalid data",
 				slog.Any("feature_id", req.FeatureID),
@@ -97,20 +238,48 @@ func New(log *slog.Logger, bannerSaver BannerSaver, redis *redis.Storage) http.H
 			render.JSON(w, r, resp.Error("Баннер уже существует"))
 			return
 		}
+		if errors.Is(err, storage.ErrIdempotencyInProgress) {
+			log.Info("request with idempotency key is completed by retry")
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Запрос с этим ключом идемпотентности уже выполняется"))
+			return
+		}
 
 		if err != nil {
-			fmt.Println(err)
-			log.Error("failed to create banner", err)
+			log.Error("failed to create banner", slog.String("error", err.Error()))
 
 			render.Status(r, 500)
 			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
 			return
 		}
 
+		completed = true
 		log.Info("banner created", slog.Int64("id", res.BannerID))
 
-		render.Status(r, 201)
+		// cache is best effort: Redis outage must not fail banner creation.
+		// Descendant tags of new banner tags may have cached banners of ancestors or
+		// not found results, so feature is flushed before banner is cached;
+		// cached banner has the same updated_at as stored one
+		if _, err := bannerSaverCache.FlushBanners(r.Context(), 0, req.FeatureID); err != nil {
+			log.Warn("failed to flush cached banners of feature",
+				slog.Int64("feature_id", req.FeatureID),
+				slog.String("error", err.Error()),
+			)
+		}
+		for _, tagID := range req.TagIDs {
+			banner := &storage.ResolvedBanner{TagID: int64(tagID), Content: req.Content, Version: 1, UpdatedAt: createdAt}
+			if err := bannerSaverCache.SetBanner(r.Context(), int64(tagID), req.FeatureID, banner); err != nil {
+				log.Warn("failed to cache banner",
+					slog.Int64("id", res.BannerID),
+					slog.Int("tag_id", tagID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		render.JSON(w, r, fmt.Sprintf(`{"banner_id": %v}`, res.BannerID))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(respBody)
 	}
 }
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	response "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	response.Response
+	Banners []*postgresql.SearchResult `json:"banners"`
+}
+
+type BannerSearcher interface {
+	SearchBanners(ctx context.Context, query postgresql.SearchQuery) ([]*postgresql.SearchResult, error)
+}
+
+// New searches banners by full-text query q over configured content fields and
+// by repeated filter parameters like `content.url contains "promo"`, results are
+// ordered by rank with matches highlighted, limit is capped by maxLimit
+func New(log *slog.Logger, bannerSearcher BannerSearcher, defaultLimit, maxLimit int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.banner.search.New"
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, response.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query, err := parseQuery(r.URL.Query(), defaultLimit, maxLimit)
+		if err != nil {
+			log.Error("invalid request query", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, response.Error("Некорректные данные"))
+			return
+		}
+		log.Info("request query parameter is valid", slog.String("q", query.Query), slog.Int("filters", len(query.Filters)))
+
+		banners, err := bannerSearcher.SearchBanners(r.Context(), query)
+		if errors.Is(err, storage.ErrBannerInvalidData) {
+			log.Error("invalid banner search", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, response.Error("Некорректные данные"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to search banners", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, response.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("banners found", slog.Int("count", len(banners)))
+
+		render.Status(r, 200)
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Banners:  banners,
+		})
+	}
+}
+
+func parseQuery(values url.Values, defaultLimit, maxLimit int64) (postgresql.SearchQuery, error) {
+	var (
+		query postgresql.SearchQuery
+		err   error
+	)
+
+	query.Query = values.Get("q")
+	for _, expr := range values["filter"] {
+		filter, err := postgresql.ParseJSONFilter(expr)
+		if err != nil {
+			return query, fmt.Errorf("request query parameter filter is invalid: %w", err)
+		}
+		query.Filters = append(query.Filters, filter)
+	}
+	if query.Query == "" && len(query.Filters) == 0 {
+		return query, fmt.Errorf("request query parameter q or filter is required")
+	}
+
+	query.Limit = defaultLimit
+	if limitStr := values.Get("limit"); limitStr != "" {
+		query.Limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("request query parameter limit is not positive integer")
+		}
+	}
+	if maxLimit > 0 && (query.Limit > maxLimit || query.Limit == 0) {
+		query.Limit = maxLimit
+	}
+
+	if offsetStr := values.Get("offset"); offsetStr != "" {
+		query.Offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || query.Offset < 0 {
+			return query, fmt.Errorf("request query parameter offset is not integer")
+		}
+	}
+
+	return query, nil
+}
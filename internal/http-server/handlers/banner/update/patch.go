@@ -0,0 +1,109 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	// MergePatchType is JSON Merge Patch (RFC 7396)
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is JSON Patch (RFC 6902)
+	JSONPatchType = "application/json-patch+json"
+)
+
+var (
+	errPatchTestFailed = errors.New("patch test operation failed")
+	errPatchNotApplied = errors.New("patch cannot be applied")
+)
+
+// document is a patchable representation of banner, paths of patches are
+// relative to it, e.g. /content/title or /tag_ids/-
+type document struct {
+	TagIDs    []int           `json:"tag_ids"`
+	FeatureID int64           `json:"feature_id"`
+	Content   json.RawMessage `json:"content"`
+	IsActive  *bool           `json:"is_active"`
+}
+
+// patchType returns media type of request if it is one of supported patch formats
+func patchType(r *http.Request) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", false
+	}
+
+	return mediaType, mediaType == MergePatchType || mediaType == JSONPatchType
+}
+
+// applyPatch applies patch to current revision of banner, it returns complete
+// banner and the revision it is based on, so update fails if banner changed meanwhile
+func applyPatch(ctx context.Context, bannerUpdater BannerUpdater, bannerID int64, mediaType string, patch []byte, ifMatch []int64) (Banner, []int64, error) {
+	current, err := bannerUpdater.GetBannerByID(ctx, bannerID)
+	if err != nil {
+		return Banner{}, nil, err
+	}
+	if !etag.Matches(current.Version, ifMatch) {
+		return Banner{}, nil, storage.ErrBannerVersion
+	}
+
+	isActive := current.IsActive
+	doc := document{
+		TagIDs:    make([]int, len(current.TagIDs)),
+		FeatureID: current.FeatureID,
+		Content:   current.Content,
+		IsActive:  &isActive,
+	}
+	for i, tagID := range current.TagIDs {
+		doc.TagIDs[i] = int(tagID)
+	}
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return Banner{}, nil, err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchType:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = ops.Apply(original)
+		}
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return Banner{}, nil, fmt.Errorf("%w: %w", errPatchTestFailed, err)
+	}
+	if err != nil {
+		return Banner{}, nil, fmt.Errorf("%w: %w", errPatchNotApplied, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	var result document
+	if err := dec.Decode(&result); err != nil {
+		return Banner{}, nil, fmt.Errorf("%w: %w", storage.ErrBannerInvalidData, err)
+	}
+	if result.TagIDs == nil || result.IsActive == nil || result.FeatureID == 0 ||
+		result.Content == nil || bytes.Equal(result.Content, []byte("null")) {
+		return Banner{}, nil, fmt.Errorf("patch removes required field: %w", storage.ErrBannerInvalidData)
+	}
+
+	return Banner{
+		TagIDs:    result.TagIDs,
+		FeatureID: result.FeatureID,
+		Content:   result.Content,
+		IsActive:  *result.IsActive,
+	}, []int64{current.Version}, nil
+}
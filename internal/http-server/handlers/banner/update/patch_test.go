@@ -0,0 +1,196 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+)
+
+// bannerStore returns current revision of banner, updates are not used by applyPatch
+type bannerStore struct {
+	banner *postgresql.Banner
+}
+
+func (s *bannerStore) UpdateBanner(ctx context.Context, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}, ifMatch []int64) (int64, int64, error) {
+	return 0, 0, errors.New("not implemented")
+}
+
+func (s *bannerStore) GetBannerByID(ctx context.Context, bannerID int64) (*postgresql.Banner, error) {
+	if s.banner == nil || s.banner.BannerID != bannerID {
+		return nil, storage.ErrBannerNotFound
+	}
+
+	return s.banner, nil
+}
+
+func newBannerStore() *bannerStore {
+	return &bannerStore{banner: &postgresql.Banner{
+		BannerID:  1,
+		TagIDs:    []int64{1, 2},
+		FeatureID: 3,
+		Content:   json.RawMessage(`{"title":"old","text":"text"}`),
+		IsActive:  true,
+		Version:   4,
+	}}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		want      Banner
+	}{
+		{
+			name:      "merge patch",
+			mediaType: MergePatchType,
+			patch:     `{"content": {"title": "new", "text": null}, "is_active": false}`,
+			want:      Banner{TagIDs: []int{1, 2}, FeatureID: 3, Content: `{"title":"new"}`, IsActive: false},
+		},
+		{
+			name:      "json patch",
+			mediaType: JSONPatchType,
+			patch: `[
+				{"op": "test", "path": "/content/title", "value": "old"},
+				{"op": "replace", "path": "/content/title", "value": "new"},
+				{"op": "add", "path": "/tag_ids/-", "value": 5},
+				{"op": "replace", "path": "/feature_id", "value": 7}
+			]`,
+			want: Banner{TagIDs: []int{1, 2, 5}, FeatureID: 7, Content: `{"text":"text","title":"new"}`, IsActive: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ifMatch, err := applyPatch(context.Background(), newBannerStore(), 1, tt.mediaType, []byte(tt.patch), nil)
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+
+			// patched banner is updated only if it is still at revision patch was applied to
+			if !reflect.DeepEqual(ifMatch, []int64{4}) {
+				t.Errorf("ifMatch = %v, want [4]", ifMatch)
+			}
+
+			var gotContent, wantContent interface{}
+			if err := json.Unmarshal(got.Content.(json.RawMessage), &gotContent); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want.Content.(string)), &wantContent); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotContent, wantContent) {
+				t.Errorf("content = %s, want %s", got.Content, tt.want.Content)
+			}
+
+			got.Content, tt.want.Content = nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("banner = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		bannerID  int64
+		mediaType string
+		patch     string
+		ifMatch   []int64
+		err       error
+	}{
+		{
+			name:      "banner not found",
+			bannerID:  2,
+			mediaType: MergePatchType,
+			patch:     `{}`,
+			err:       storage.ErrBannerNotFound,
+		},
+		{
+			name:      "stale version",
+			bannerID:  1,
+			mediaType: MergePatchType,
+			patch:     `{"is_active": false}`,
+			ifMatch:   []int64{3},
+			err:       storage.ErrBannerVersion,
+		},
+		{
+			name:      "failed test operation",
+			bannerID:  1,
+			mediaType: JSONPatchType,
+			patch:     `[{"op": "test", "path": "/content/title", "value": "other"}]`,
+			err:       errPatchTestFailed,
+		},
+		{
+			name:      "missing path",
+			bannerID:  1,
+			mediaType: JSONPatchType,
+			patch:     `[{"op": "remove", "path": "/content/missing"}]`,
+			err:       errPatchNotApplied,
+		},
+		{
+			name:      "malformed patch",
+			bannerID:  1,
+			mediaType: MergePatchType,
+			patch:     `{"content":`,
+			err:       errPatchNotApplied,
+		},
+		{
+			name:      "removed required field",
+			bannerID:  1,
+			mediaType: MergePatchType,
+			patch:     `{"content": null}`,
+			err:       storage.ErrBannerInvalidData,
+		},
+		{
+			name:      "unknown field",
+			bannerID:  1,
+			mediaType: JSONPatchType,
+			patch:     `[{"op": "add", "path": "/owner", "value": "me"}]`,
+			err:       storage.ErrBannerInvalidData,
+		},
+		{
+			name:      "wrong type",
+			bannerID:  1,
+			mediaType: MergePatchType,
+			patch:     `{"tag_ids": "1,2"}`,
+			err:       storage.ErrBannerInvalidData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := applyPatch(context.Background(), newBannerStore(), tt.bannerID, tt.mediaType, []byte(tt.patch), tt.ifMatch)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("applyPatch error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPatchType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		ok          bool
+	}{
+		{contentType: "application/merge-patch+json", want: MergePatchType, ok: true},
+		{contentType: "application/json-patch+json; charset=utf-8", want: JSONPatchType, ok: true},
+		{contentType: "application/json", want: "application/json"},
+		{contentType: ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PATCH", "/banner/1", nil)
+		r.Header.Set("Content-Type", tt.contentType)
+
+		got, ok := patchType(r)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("patchType(%q) = %q, %t, want %q, %t", tt.contentType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
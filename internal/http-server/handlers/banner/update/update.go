@@ -2,6 +2,7 @@ package update
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -9,8 +10,11 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
 	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
 	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
@@ -34,10 +38,26 @@ type Banner struct {
 }
 
 type BannerUpdater interface {
-	UpdateBanner(ctx context.Context, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}) error
+	UpdateBanner(ctx context.Context, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}, ifMatch []int64) (int64, int64, error)
+	GetBannerByID(ctx context.Context, bannerID int64) (*postgresql.Banner, error)
 }
 
-func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
+type CacheFlusher interface {
+	FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error)
+}
+
+type ContentValidator interface {
+	ValidateContent(ctx context.Context, featureID int64, content []byte) ([]schema.FieldError, error)
+}
+
+// New updates banner in one transaction: tag_ids replace tag set of the banner
+// and a tag and feature pair of another banner is a conflict; if content or
+// feature changes, resulting content is validated against JSON Schema of
+// resulting feature; with If-Match the banner is updated only if its version
+// still matches, new version is returned in ETag; bodies of MergePatchType and
+// JSONPatchType are applied to the current revision of the banner; cached
+// banners of its previous and new feature are flushed
+func New(log *slog.Logger, bannerUpdater BannerUpdater, contentValidator ContentValidator, cacheFlusher CacheFlusher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.banner.update.New"
 
@@ -47,7 +67,6 @@ func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
 		)
 
 		_, claims, _ := jwtauth.FromContext(r.Context())
-		fmt.Println(claims)
 		if claims["role"] != "admin" {
 			render.Status(r, 403)
 			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
@@ -60,8 +79,7 @@ func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
 		)
 		req.BannerID, err = strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 		if err != nil {
-			log.Error("request path parameter id is not integer")
-			fmt.Println(chi.URLParam(r, "id"))
+			log.Error("request path parameter id is not integer", slog.String("id", chi.URLParam(r, "id")))
 
 			render.Status(r, 400)
 			render.JSON(w, r, resp.Error("Некорректные данные"))
@@ -69,25 +87,122 @@ func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
 		}
 		log.Info("path parameter is valid", slog.Any("request", req))
 
-		err = render.DecodeJSON(r.Body, &req.Banner)
-		// checking for an empty request body
-		if errors.Is(err, io.EOF) {
-			log.Error("request body is empty")
+		ifMatch, err := etag.Versions(r.Header.Get("If-Match"))
+		if err != nil {
+			log.Error("invalid If-Match header", slog.String("error", err.Error()))
 
 			render.Status(r, 400)
 			render.JSON(w, r, resp.Error("Некорректные данные"))
 			return
 		}
-		if err != nil {
-			log.Error("failed to decode request body", err)
 
-			render.Status(r, 400)
-			render.JSON(w, r, resp.Error("Некорректные данные"))
-			return
+		if mediaType, ok := patchType(r); ok {
+			patch, err := io.ReadAll(r.Body)
+			if err != nil || len(patch) == 0 {
+				log.Error("request body is empty")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+
+			req.Banner, ifMatch, err = applyPatch(r.Context(), bannerUpdater, req.BannerID, mediaType, patch, ifMatch)
+			switch {
+			case err == nil:
+			case errors.Is(err, storage.ErrBannerNotExists):
+				log.Info("banner not exists", slog.Int64("id", req.BannerID))
+
+				render.Status(r, 404)
+				render.JSON(w, r, resp.Error("Баннер не найден"))
+				return
+			case errors.Is(err, storage.ErrBannerVersion):
+				log.Info("banner version mismatch", slog.Int64("id", req.BannerID), slog.Any("if_match", ifMatch))
+
+				render.Status(r, 412)
+				render.JSON(w, r, resp.Error("Баннер был изменен"))
+				return
+			case errors.Is(err, errPatchTestFailed):
+				log.Info("patch test failed", slog.String("error", err.Error()))
+
+				render.Status(r, 409)
+				render.JSON(w, r, resp.Error("Баннер не соответствует условию патча"))
+				return
+			case errors.Is(err, errPatchNotApplied):
+				log.Info("patch cannot be applied", slog.String("error", err.Error()))
+
+				render.Status(r, 422)
+				render.JSON(w, r, resp.Error("Патч не может быть применен"))
+				return
+			case errors.Is(err, storage.ErrBannerInvalidData):
+				log.Info("patched banner is invalid", slog.String("error", err.Error()))
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			default:
+				log.Error("failed to apply patch", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+			log.Info("patch applied", slog.String("content_type", mediaType), slog.Any("request", req))
+		} else {
+			err = render.DecodeJSON(r.Body, &req.Banner)
+			// checking for an empty request body
+			if errors.Is(err, io.EOF) {
+				log.Error("request body is empty")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+			log.Info("request body decoded", slog.Any("request", req))
+		}
+
+		if req.Banner.Content != nil || req.Banner.FeatureID != 0 {
+			violations, err := validateContent(r.Context(), bannerUpdater, contentValidator, req)
+			if errors.Is(err, storage.ErrBannerNotExists) {
+				log.Info("banner not exists", slog.Int64("id", req.BannerID))
+
+				render.Status(r, 404)
+				render.JSON(w, r, resp.Error("Баннер не найден"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to validate content", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+			if len(violations) > 0 {
+				log.Info("content does not match feature schema",
+					slog.Int64("id", req.BannerID),
+					slog.Any("violations", violations),
+				)
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.ValidationError("Некорректные данные", violations))
+				return
+			}
 		}
-		log.Info("request body decoded", slog.Any("request", req))
 
-		err = bannerUpdater.UpdateBanner(r.Context(), req.BannerID, req.Banner.FeatureID, req.Banner.TagIDs, req.Banner.Content, req.Banner.IsActive)
+		version, previousFeatureID, err := bannerUpdater.UpdateBanner(r.Context(), req.BannerID, req.Banner.FeatureID, req.Banner.TagIDs, req.Banner.Content, req.Banner.IsActive, ifMatch)
+		if errors.Is(err, storage.ErrBannerVersion) {
+			log.Info("banner version mismatch", slog.Int64("id", req.BannerID), slog.Any("if_match", ifMatch))
+
+			render.Status(r, 412)
+			render.JSON(w, r, resp.Error("Баннер был изменен"))
+			return
+		}
 		if errors.Is(err, storage.ErrBannerInvalidData) {
 			log.Info("banner with invalid data",
 				slog.Any("feature_id", req.Banner.FeatureID),
@@ -100,6 +215,17 @@ func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
 			render.JSON(w, r, resp.Error("Некорректные данные"))
 			return
 		}
+		if errors.Is(err, storage.ErrBannerExists) {
+			log.Info("tag and feature pair is taken by another banner",
+				slog.Int64("id", req.BannerID),
+				slog.Any("feature_id", req.Banner.FeatureID),
+				slog.Any("tag_ids", req.Banner.TagIDs),
+			)
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Баннер для тега и фичи уже существует"))
+			return
+		}
 		if errors.Is(err, storage.ErrBannerNotExists) {
 			log.Info("banner not exists",
 				slog.Any("feature_id", req.Banner.FeatureID),
@@ -113,16 +239,51 @@ func New(log *slog.Logger, bannerUpdater BannerUpdater) http.HandlerFunc {
 			return
 		}
 		if err != nil {
-			fmt.Println(err)
-			log.Error("failed to update banner", err)
+			log.Error("failed to update banner", slog.String("error", err.Error()))
 
 			render.Status(r, 500)
 			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
 			return
 		}
 
-		log.Info("banner update", slog.Int64("id", req.BannerID))
+		log.Info("banner update", slog.Int64("id", req.BannerID), slog.Int64("version", version))
 
+		// banner may be cached for descendant tags as well, cache is best effort
+		featureIDs := []int64{previousFeatureID}
+		if req.Banner.FeatureID != 0 && req.Banner.FeatureID != previousFeatureID {
+			featureIDs = append(featureIDs, req.Banner.FeatureID)
+		}
+		for _, featureID := range featureIDs {
+			if _, err := cacheFlusher.FlushBanners(r.Context(), 0, featureID); err != nil {
+				log.Warn("failed to flush cached banners of feature",
+					slog.Int64("feature_id", featureID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		w.Header().Set("ETag", etag.Version(version))
 		fmt.Fprintln(w, http.StatusOK)
 	}
 }
+
+// validateContent checks content of banner as it will be after update
+func validateContent(ctx context.Context, bannerUpdater BannerUpdater, contentValidator ContentValidator, req Request) ([]schema.FieldError, error) {
+	banner, err := bannerUpdater.GetBannerByID(ctx, req.BannerID)
+	if err != nil {
+		return nil, err
+	}
+
+	featureID := banner.FeatureID
+	if req.Banner.FeatureID != 0 {
+		featureID = req.Banner.FeatureID
+	}
+	content := []byte(banner.Content)
+	if req.Banner.Content != nil {
+		if content, err = json.Marshal(req.Banner.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return contentValidator.ValidateContent(ctx, featureID, content)
+}
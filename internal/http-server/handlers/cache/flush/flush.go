@@ -0,0 +1,100 @@
+package flush
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	FeatureID int64 `json:"feature_id"`
+	TagID     int64 `json:"tag_id"`
+}
+
+type Response struct {
+	resp.Response
+	Deleted int64 `json:"deleted"`
+}
+
+type CacheFlusher interface {
+	FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error)
+}
+
+func New(log *slog.Logger, cacheFlusher CacheFlusher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.cache.flush.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		var (
+			req Request
+			err error
+		)
+
+		featureIDStr := r.URL.Query().Get("feature_id")
+		if featureIDStr != "" {
+			req.FeatureID, err = strconv.ParseInt(featureIDStr, 10, 64)
+			if err != nil {
+				log.Error("request query parameter feature_id is not integer")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		tagIDStr := r.URL.Query().Get("tag_id")
+		if tagIDStr != "" {
+			req.TagID, err = strconv.ParseInt(tagIDStr, 10, 64)
+			if err != nil {
+				log.Error("request query parameter tag_id is not integer")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+		log.Info("request query parameter is valid", slog.Any("request", req))
+
+		var res Response
+		res.Deleted, err = cacheFlusher.FlushBanners(r.Context(), req.TagID, req.FeatureID)
+		if errors.Is(err, storage.ErrCacheUnavailable) {
+			log.Warn("Redis is unavailable")
+
+			render.Status(r, 503)
+			render.JSON(w, r, resp.Error("Кеш недоступен"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to flush cache", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("cache flushed", slog.Int64("deleted", res.Deleted))
+
+		res.Response = resp.OK()
+		render.Status(r, 200)
+		render.JSON(w, r, res)
+	}
+}
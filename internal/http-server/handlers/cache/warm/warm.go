@@ -0,0 +1,71 @@
+package warm
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/JustForWorld/banner-shift/internal/cache/warmup"
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Progress warmup.Progress `json:"progress"`
+}
+
+type CacheWarmer interface {
+	Start(ctx context.Context) (warmup.Progress, bool)
+	Progress() warmup.Progress
+}
+
+// New starts cache warm-up in background, progress is available with Progress
+func New(log *slog.Logger, cacheWarmer CacheWarmer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.cache.warm.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		// warm-up outlives the request
+		progress, started := cacheWarmer.Start(context.Background())
+		if !started {
+			log.Info("cache warm-up is already running")
+
+			render.Status(r, 409)
+			render.JSON(w, r, Response{Response: resp.Error("Прогрев кеша уже выполняется"), Progress: progress})
+			return
+		}
+
+		log.Info("cache warm-up started")
+
+		render.Status(r, 202)
+		render.JSON(w, r, Response{Response: resp.OK(), Progress: progress})
+	}
+}
+
+func Progress(cacheWarmer CacheWarmer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		render.Status(r, 200)
+		render.JSON(w, r, Response{Response: resp.OK(), Progress: cacheWarmer.Progress()})
+	}
+}
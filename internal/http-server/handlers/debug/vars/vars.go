@@ -0,0 +1,37 @@
+package vars
+
+import (
+	"expvar"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// New serves expvar metrics (cache state, config reloads, memstats and cmdline) to admin
+func New(log *slog.Logger) http.HandlerFunc {
+	vars := expvar.Handler()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.debug.vars.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			log.Info("debug vars are requested by non-admin user")
+
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		vars.ServeHTTP(w, r)
+	}
+}
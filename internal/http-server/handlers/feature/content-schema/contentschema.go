@@ -0,0 +1,225 @@
+package contentschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type ValidateRequest struct {
+	Content json.RawMessage `json:"content"`
+}
+
+type ValidateResponse struct {
+	resp.Response
+	Valid   bool                `json:"valid"`
+	Details []schema.FieldError `json:"details"`
+}
+
+type ContentSchemaSetter interface {
+	SetFeatureContentSchema(ctx context.Context, featureID int64, schema []byte) error
+}
+
+type FeatureGetter interface {
+	GetFeature(ctx context.Context, featureID int64) (*postgresql.Feature, error)
+}
+
+// New sets JSON Schema banner content of feature must match, request body is the schema
+func New(log *slog.Logger, contentSchemaSetter ContentSchemaSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.contentSchema.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		var source json.RawMessage
+		err = render.DecodeJSON(r.Body, &source)
+		// checking for an empty request body
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		if _, err := schema.Compile(source); err != nil {
+			log.Info("invalid content schema", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректная JSON Schema"))
+			return
+		}
+
+		setContentSchema(w, r, log, contentSchemaSetter, featureID, source)
+	}
+}
+
+// Delete removes JSON Schema of feature
+func Delete(log *slog.Logger, contentSchemaSetter ContentSchemaSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.contentSchema.Delete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		setContentSchema(w, r, log, contentSchemaSetter, featureID, nil)
+	}
+}
+
+// Validate checks content against JSON Schema of feature without saving anything
+func Validate(log *slog.Logger, featureGetter FeatureGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.contentSchema.Validate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		var req ValidateRequest
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil || req.Content == nil {
+			log.Error("failed to decode request body")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		feature, err := featureGetter.GetFeature(r.Context(), featureID)
+		if errors.Is(err, storage.ErrFeatureNotFound) {
+			log.Info("feature not found", slog.Int64("id", featureID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Фича не найдена"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get feature", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		violations := []schema.FieldError{}
+		if feature.ContentSchema != nil {
+			sch, err := schema.Compile(feature.ContentSchema)
+			if err != nil {
+				log.Error("failed to compile content schema", slog.String("error", err.Error()))
+
+				render.Status(r, 500)
+				render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+				return
+			}
+			if errs := schema.Validate(sch, req.Content); errs != nil {
+				violations = errs
+			}
+		}
+
+		log.Info("content validated", slog.Int64("id", featureID), slog.Int("violations", len(violations)))
+
+		render.Status(r, 200)
+		render.JSON(w, r, ValidateResponse{
+			Response: resp.OK(),
+			Valid:    len(violations) == 0,
+			Details:  violations,
+		})
+	}
+}
+
+func setContentSchema(w http.ResponseWriter, r *http.Request, log *slog.Logger, contentSchemaSetter ContentSchemaSetter, featureID int64, source []byte) {
+	err := contentSchemaSetter.SetFeatureContentSchema(r.Context(), featureID, source)
+	if errors.Is(err, storage.ErrFeatureNotFound) {
+		log.Info("feature not found", slog.Int64("id", featureID))
+
+		render.Status(r, 404)
+		render.JSON(w, r, resp.Error("Фича не найдена"))
+		return
+	}
+	if err != nil {
+		log.Error("failed to set content schema", slog.String("error", err.Error()))
+
+		render.Status(r, 500)
+		render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+		return
+	}
+
+	log.Info("content schema set", slog.Int64("id", featureID), slog.Bool("removed", source == nil))
+
+	render.Status(r, 200)
+	render.JSON(w, r, resp.OK())
+}
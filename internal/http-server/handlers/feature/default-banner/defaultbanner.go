@@ -0,0 +1,160 @@
+package defaultbanner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	BannerID int64 `json:"banner_id"`
+}
+
+type DefaultBannerSetter interface {
+	SetFeatureDefaultBanner(ctx context.Context, featureID, bannerID int64) error
+}
+
+type CacheFlusher interface {
+	FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error)
+}
+
+// New sets default banner of feature, it is served for tags without own banner;
+// cached banners of feature are flushed, so fallback is applied at once
+func New(log *slog.Logger, defaultBannerSetter DefaultBannerSetter, cacheFlusher CacheFlusher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.defaultBanner.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		var req Request
+		err = render.DecodeJSON(r.Body, &req)
+		// checking for an empty request body
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if err != nil || req.BannerID <= 0 {
+			log.Error("failed to decode request body")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		setDefaultBanner(w, r, log, defaultBannerSetter, cacheFlusher, featureID, req.BannerID)
+	}
+}
+
+// Delete removes default banner of feature
+func Delete(log *slog.Logger, defaultBannerSetter DefaultBannerSetter, cacheFlusher CacheFlusher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.defaultBanner.Delete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		setDefaultBanner(w, r, log, defaultBannerSetter, cacheFlusher, featureID, 0)
+	}
+}
+
+func setDefaultBanner(w http.ResponseWriter, r *http.Request, log *slog.Logger, defaultBannerSetter DefaultBannerSetter, cacheFlusher CacheFlusher, featureID, bannerID int64) {
+	err := defaultBannerSetter.SetFeatureDefaultBanner(r.Context(), featureID, bannerID)
+	if errors.Is(err, storage.ErrFeatureNotFound) {
+		log.Info("feature not found", slog.Int64("id", featureID))
+
+		render.Status(r, 404)
+		render.JSON(w, r, resp.Error("Фича не найдена"))
+		return
+	}
+	if errors.Is(err, storage.ErrBannerNotExists) {
+		log.Info("banner not exists", slog.Int64("banner_id", bannerID))
+
+		render.Status(r, 404)
+		render.JSON(w, r, resp.Error("Баннер не найден"))
+		return
+	}
+	if errors.Is(err, storage.ErrBannerInvalidData) {
+		log.Info("banner belongs to another feature",
+			slog.Int64("id", featureID),
+			slog.Int64("banner_id", bannerID),
+		)
+
+		render.Status(r, 400)
+		render.JSON(w, r, resp.Error("Баннер относится к другой фиче"))
+		return
+	}
+	if err != nil {
+		log.Error("failed to set default banner", slog.String("error", err.Error()))
+
+		render.Status(r, 500)
+		render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+		return
+	}
+
+	log.Info("default banner set",
+		slog.Int64("id", featureID),
+		slog.Int64("banner_id", bannerID),
+	)
+
+	// cached results of tags, including not found ones, are resolved with previous default;
+	// cache is best effort, they expire with their ttl if Redis is unavailable
+	if _, err := cacheFlusher.FlushBanners(r.Context(), 0, featureID); err != nil {
+		log.Warn("failed to flush cached banners of feature",
+			slog.Int64("id", featureID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	render.Status(r, 200)
+	render.JSON(w, r, resp.OK())
+}
@@ -0,0 +1,67 @@
+package get
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type FeatureGetter interface {
+	GetFeature(ctx context.Context, featureID int64) (*postgresql.Feature, error)
+}
+
+func New(log *slog.Logger, featureGetter FeatureGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.get.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		feature, err := featureGetter.GetFeature(r.Context(), featureID)
+		if errors.Is(err, storage.ErrFeatureNotFound) {
+			log.Info("feature not found", slog.Int64("id", featureID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Фича не найдена"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get feature", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		render.Status(r, 200)
+		render.JSON(w, r, feature)
+	}
+}
@@ -0,0 +1,135 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Owner       *string `json:"owner"`
+	Archived    *bool   `json:"archived"`
+}
+
+type FeatureUpdater interface {
+	UpdateFeature(ctx context.Context, featureID int64, upd postgresql.MetaUpdate, force bool) error
+}
+
+// New updates feature, archiving a feature with active banners requires force=true
+func New(log *slog.Logger, featureUpdater FeatureUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.feature.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		featureID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		force := false
+		if forceStr := r.URL.Query().Get("force"); forceStr != "" {
+			force, err = strconv.ParseBool(forceStr)
+			if err != nil {
+				log.Error("request query parameter force is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		var req Request
+		err = render.DecodeJSON(r.Body, &req)
+		// checking for an empty request body
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		log.Info("request body decoded", slog.Any("request", req))
+
+		err = featureUpdater.UpdateFeature(r.Context(), featureID, postgresql.MetaUpdate{
+			Name:        req.Name,
+			Description: req.Description,
+			Owner:       req.Owner,
+			Archived:    req.Archived,
+		}, force)
+		if errors.Is(err, storage.ErrBannerInvalidData) {
+			log.Info("feature with invalid data", slog.Int64("id", featureID))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if errors.Is(err, storage.ErrFeatureNotFound) {
+			log.Info("feature not found", slog.Int64("id", featureID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Фича не найдена"))
+			return
+		}
+		if errors.Is(err, storage.ErrFeatureExists) {
+			log.Info("feature exists", slog.Int64("id", featureID))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Фича с таким именем уже существует"))
+			return
+		}
+		if errors.Is(err, storage.ErrFeatureHasActiveBanners) {
+			log.Info("feature has active banners", slog.Int64("id", featureID))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("У фичи есть активные баннеры, используйте force=true"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update feature", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("feature updated", slog.Int64("id", featureID))
+
+		render.Status(r, 200)
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	StateOK       = "ok"
+	StateDegraded = "degraded"
+	StateDown     = "down"
+)
+
+type Response struct {
+	resp.Response
+	PostgreSQL string `json:"postgres"`
+	Redis      string `json:"redis"`
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type CacheState interface {
+	Degraded() bool
+}
+
+// New is a readiness probe: the service is ready while PostgreSQL is
+// reachable, Redis outage is only reported as degraded state
+func New(log *slog.Logger, pinger Pinger, cacheState CacheState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		res := Response{
+			Response:   resp.OK(),
+			PostgreSQL: StateOK,
+			Redis:      StateOK,
+		}
+		status := http.StatusOK
+
+		if cacheState.Degraded() {
+			res.Redis = StateDegraded
+		}
+
+		if err := pinger.Ping(r.Context()); err != nil {
+			log.Error("PostgreSQL is unavailable", slog.String("error", err.Error()))
+
+			res.Response = resp.Error("PostgreSQL недоступен")
+			res.PostgreSQL = StateDown
+			status = http.StatusServiceUnavailable
+		}
+
+		render.Status(r, status)
+		render.JSON(w, r, res)
+	}
+}
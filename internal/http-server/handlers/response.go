@@ -1,5 +1,7 @@
 package response
 
+import "github.com/JustForWorld/banner-shift/internal/schema"
+
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
@@ -22,3 +24,16 @@ func Error(msg string) Response {
 		Error:  msg,
 	}
 }
+
+// ValidationResponse lists fields of request that failed validation
+type ValidationResponse struct {
+	Response
+	Details []schema.FieldError `json:"details"`
+}
+
+func ValidationError(msg string, details []schema.FieldError) ValidationResponse {
+	return ValidationResponse{
+		Response: Error(msg),
+		Details:  details,
+	}
+}
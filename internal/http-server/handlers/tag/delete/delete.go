@@ -0,0 +1,74 @@
+package delete
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type TagRemove interface {
+	DeleteTag(ctx context.Context, tagID int64) error
+}
+
+func New(log *slog.Logger, tagRemove TagRemove) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tag.delete.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		tagID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		err = tagRemove.DeleteTag(r.Context(), tagID)
+		if errors.Is(err, storage.ErrTagNotFound) {
+			log.Info("tag not found", slog.Int64("id", tagID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Тег не найден"))
+			return
+		}
+		if errors.Is(err, storage.ErrTagInUse) {
+			log.Info("tag is used by banners", slog.Int64("id", tagID))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Тег используется баннерами"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete tag", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("tag removed", slog.Int64("id", tagID))
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
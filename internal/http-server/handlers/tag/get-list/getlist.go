@@ -0,0 +1,91 @@
+package getlist
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	IncludeArchived bool  `json:"include_archived"`
+	Limit           int64 `json:"limit"`
+	Offset          int64 `json:"offset"`
+}
+
+type TagGetterList interface {
+	GetTagList(ctx context.Context, includeArchived bool, limit, offset int64) ([]*postgresql.Tag, error)
+}
+
+func New(log *slog.Logger, tagGetterList TagGetterList) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tag.getList.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		var (
+			req Request
+			err error
+		)
+
+		if archivedStr := r.URL.Query().Get("include_archived"); archivedStr != "" {
+			req.IncludeArchived, err = strconv.ParseBool(archivedStr)
+			if err != nil {
+				log.Error("request query parameter include_archived is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			req.Limit, err = strconv.ParseInt(limitStr, 10, 64)
+			if err != nil || req.Limit < 0 {
+				log.Error("request query parameter limit is not integer")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+		if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+			req.Offset, err = strconv.ParseInt(offsetStr, 10, 64)
+			if err != nil || req.Offset < 0 {
+				log.Error("request query parameter offset is not integer")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		tags, err := tagGetterList.GetTagList(r.Context(), req.IncludeArchived, req.Limit, req.Offset)
+		if err != nil {
+			log.Error("failed to get tags", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("tags found", slog.Int("count", len(tags)))
+		render.Status(r, 200)
+		render.JSON(w, r, tags)
+	}
+}
@@ -0,0 +1,98 @@
+package save
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	ParentID    int64  `json:"parent_id"`
+}
+
+type Response struct {
+	resp.Response
+	TagID int64 `json:"tag_id"`
+}
+
+type TagSaver interface {
+	CreateTag(ctx context.Context, name, description, owner string, parentID int64) (int64, error)
+}
+
+func New(log *slog.Logger, tagSaver TagSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tag.save.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		// checking for an empty request body
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		log.Info("request body decoded", slog.Any("request", req))
+
+		var res Response
+		res.TagID, err = tagSaver.CreateTag(r.Context(), req.Name, req.Description, req.Owner, req.ParentID)
+		if errors.Is(err, storage.ErrBannerInvalidData) || errors.Is(err, storage.ErrTagInvalidParent) {
+			log.Info("tag with invalid data", slog.Any("request", req))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if errors.Is(err, storage.ErrTagExists) {
+			log.Info("tag exists", slog.String("name", req.Name))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Тег с таким именем уже существует"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to create tag", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("tag created", slog.Int64("id", res.TagID))
+
+		res.Response = resp.OK()
+		render.Status(r, 201)
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,137 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/JustForWorld/banner-shift/internal/storage/postgresql"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+type Request struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Owner       *string `json:"owner"`
+	Archived    *bool   `json:"archived"`
+	// ParentID 0 makes tag top level
+	ParentID *int64 `json:"parent_id"`
+}
+
+type TagUpdater interface {
+	UpdateTag(ctx context.Context, tagID int64, upd postgresql.MetaUpdate, parentID *int64, force bool) error
+}
+
+// New updates tag, archiving a tag with active banners requires force=true
+func New(log *slog.Logger, tagUpdater TagUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tag.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		if claims["role"] != "admin" {
+			render.Status(r, 403)
+			render.JSON(w, r, resp.Error("Пользователь не имеет доступа"))
+			return
+		}
+
+		tagID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("request path parameter id is not integer")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+
+		force := false
+		if forceStr := r.URL.Query().Get("force"); forceStr != "" {
+			force, err = strconv.ParseBool(forceStr)
+			if err != nil {
+				log.Error("request query parameter force is not boolean")
+
+				render.Status(r, 400)
+				render.JSON(w, r, resp.Error("Некорректные данные"))
+				return
+			}
+		}
+
+		var req Request
+		err = render.DecodeJSON(r.Body, &req)
+		// checking for an empty request body
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		log.Info("request body decoded", slog.Any("request", req))
+
+		err = tagUpdater.UpdateTag(r.Context(), tagID, postgresql.MetaUpdate{
+			Name:        req.Name,
+			Description: req.Description,
+			Owner:       req.Owner,
+			Archived:    req.Archived,
+		}, req.ParentID, force)
+		if errors.Is(err, storage.ErrBannerInvalidData) || errors.Is(err, storage.ErrTagInvalidParent) {
+			log.Info("tag with invalid data", slog.Int64("id", tagID))
+
+			render.Status(r, 400)
+			render.JSON(w, r, resp.Error("Некорректные данные"))
+			return
+		}
+		if errors.Is(err, storage.ErrTagNotFound) {
+			log.Info("tag not found", slog.Int64("id", tagID))
+
+			render.Status(r, 404)
+			render.JSON(w, r, resp.Error("Тег не найден"))
+			return
+		}
+		if errors.Is(err, storage.ErrTagExists) {
+			log.Info("tag exists", slog.Int64("id", tagID))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("Тег с таким именем уже существует"))
+			return
+		}
+		if errors.Is(err, storage.ErrTagHasActiveBanners) {
+			log.Info("tag has active banners", slog.Int64("id", tagID))
+
+			render.Status(r, 409)
+			render.JSON(w, r, resp.Error("У тега есть активные баннеры, используйте force=true"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update tag", slog.String("error", err.Error()))
+
+			render.Status(r, 500)
+			render.JSON(w, r, resp.Error("Внутренняя ошибка сервера"))
+			return
+		}
+
+		log.Info("tag updated", slog.Int64("id", tagID))
+
+		render.Status(r, 200)
+		render.JSON(w, r, resp.OK())
+	}
+}
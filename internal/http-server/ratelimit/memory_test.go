@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMemory() (*Memory, *clock) {
+	c := &clock{now: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemory()
+	m.now = func() time.Time { return c.now }
+	m.lastSweep = c.now
+
+	return m, c
+}
+
+func take(t *testing.T, m *Memory, key string, rate float64, burst int) (bool, float64) {
+	t.Helper()
+
+	allowed, remaining, err := m.TakeToken(context.Background(), key, rate, burst)
+	if err != nil {
+		t.Fatalf("TakeToken: %v", err)
+	}
+
+	return allowed, remaining
+}
+
+func TestMemoryBurst(t *testing.T) {
+	m, _ := newTestMemory()
+
+	for want := 2.0; want >= 0; want-- {
+		allowed, remaining := take(t, m, "a", 1, 3)
+		if !allowed || remaining != want {
+			t.Fatalf("TakeToken = %t, %v, want true, %v", allowed, remaining, want)
+		}
+	}
+	if allowed, _ := take(t, m, "a", 1, 3); allowed {
+		t.Error("TakeToken over burst is allowed")
+	}
+
+	// buckets of different keys are separate
+	if allowed, remaining := take(t, m, "b", 1, 3); !allowed || remaining != 2 {
+		t.Errorf("TakeToken of another key = %t, %v, want true, 2", allowed, remaining)
+	}
+}
+
+func TestMemoryRefill(t *testing.T) {
+	m, c := newTestMemory()
+
+	for i := 0; i < 2; i++ {
+		take(t, m, "a", 2, 2)
+	}
+	if allowed, _ := take(t, m, "a", 2, 2); allowed {
+		t.Fatal("TakeToken of empty bucket is allowed")
+	}
+
+	// 2 tokens per second refill one token in 500ms
+	c.advance(250 * time.Millisecond)
+	if allowed, remaining := take(t, m, "a", 2, 2); allowed || remaining != 0.5 {
+		t.Errorf("TakeToken after 250ms = %t, %v, want false, 0.5", allowed, remaining)
+	}
+	c.advance(250 * time.Millisecond)
+	if allowed, remaining := take(t, m, "a", 2, 2); !allowed || remaining != 0 {
+		t.Errorf("TakeToken after 500ms = %t, %v, want true, 0", allowed, remaining)
+	}
+
+	// bucket is not filled over burst
+	c.advance(time.Hour)
+	if allowed, remaining := take(t, m, "a", 2, 2); !allowed || remaining != 1 {
+		t.Errorf("TakeToken after hour = %t, %v, want true, 1", allowed, remaining)
+	}
+}
+
+func TestMemoryLimitChange(t *testing.T) {
+	m, _ := newTestMemory()
+
+	take(t, m, "a", 1, 5)
+	// lowered burst caps tokens left in bucket
+	if allowed, remaining := take(t, m, "a", 1, 2); !allowed || remaining != 1 {
+		t.Errorf("TakeToken with lower burst = %t, %v, want true, 1", allowed, remaining)
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m, c := newTestMemory()
+
+	take(t, m, "full", 1, 1)
+	take(t, m, "slow", 0.001, 2)
+	take(t, m, "slow", 0.001, 2)
+
+	c.advance(sweepInterval)
+	take(t, m, "new", 1, 1)
+
+	if _, ok := m.buckets["full"]; ok {
+		t.Error("refilled bucket is not swept")
+	}
+	if _, ok := m.buckets["slow"]; !ok {
+		t.Error("bucket which is not full is swept")
+	}
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	resp "github.com/JustForWorld/banner-shift/internal/http-server/handlers"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+)
+
+// scopes have separate limits and buckets
+const (
+	ScopeUserBanner = "user_banner"
+	ScopeAdmin      = "admin"
+)
+
+// Limit is a token bucket: Burst requests at once, then Rate requests per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type TokenTaker interface {
+	TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+}
+
+// Limiter throttles requests per JWT subject, or per client IP without token
+type Limiter struct {
+	log      *slog.Logger
+	store    TokenTaker
+	fallback *Memory
+	settings atomic.Pointer[settings]
+}
+
+type settings struct {
+	enabled bool
+	limits  map[string]Limit
+}
+
+// New returns limiter over store, buckets of a single node are used while store fails
+func New(log *slog.Logger, store TokenTaker) *Limiter {
+	l := &Limiter{
+		log:      log,
+		store:    store,
+		fallback: NewMemory(),
+	}
+	l.Set(false, nil)
+
+	return l
+}
+
+// Set replaces limits by scope, it is safe while requests are served
+func (l *Limiter) Set(enabled bool, limits map[string]Limit) {
+	l.settings.Store(&settings{enabled: enabled, limits: limits})
+}
+
+// Limit returns middleware limiting requests of scope, it must follow jwtauth.Verifier
+// and precede jwtauth.Authenticator, so requests without valid token are limited by IP
+func (l *Limiter) Limit(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "ratelimit.Limiter.Limit"
+
+			cfg := l.settings.Load()
+			limit, ok := cfg.limits[scope]
+			if !cfg.enabled || !ok || limit.Rate <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := scope + ":" + subject(r)
+			allowed, remaining, err := l.store.TakeToken(r.Context(), key, limit.Rate, limit.Burst)
+			if err != nil {
+				if !errors.Is(err, storage.ErrCacheUnavailable) {
+					l.log.Warn("failed to take rate limit token",
+						slog.String("op", op),
+						slog.String("request_id", middleware.GetReqID(r.Context())),
+						slog.String("error", err.Error()),
+					)
+				}
+				allowed, remaining, _ = l.fallback.TakeToken(r.Context(), key, limit.Rate, limit.Burst)
+			}
+
+			// RateLimit-* headers follow draft-ietf-httpapi-ratelimit-headers
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(remaining)))))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(math.Max(0, float64(limit.Burst)-remaining)/limit.Rate))))
+
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds((1-remaining)/limit.Rate)))
+
+				render.Status(r, 429)
+				render.JSON(w, r, resp.Error("Слишком много запросов"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// subject is "sub" or "username" claim of token, otherwise client IP
+func subject(r *http.Request) string {
+	if _, claims, err := jwtauth.FromContext(r.Context()); err == nil {
+		for _, claim := range []string{"sub", "username"} {
+			if sub, ok := claims[claim].(string); ok && sub != "" {
+				return "sub:" + sub
+			}
+		}
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return "ip:" + ip
+}
+
+// seconds rounds up to whole seconds, at least 1
+func seconds(s float64) int {
+	return int(math.Max(1, math.Ceil(s)))
+}
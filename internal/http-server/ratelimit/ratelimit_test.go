@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+func TestLimit(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("test-secret"), nil)
+	_, alice, _ := tokenAuth.Encode(map[string]interface{}{"username": "alice"})
+	_, bob, _ := tokenAuth.Encode(map[string]interface{}{"username": "bob"})
+
+	limiter := New(slog.New(slog.NewTextHandler(io.Discard, nil)), NewMemory())
+	limiter.Set(true, map[string]Limit{ScopeUserBanner: {Rate: 0.001, Burst: 1}})
+
+	router := chi.NewRouter()
+	router.Use(jwtauth.Verifier(tokenAuth))
+	router.Use(limiter.Limit(ScopeUserBanner))
+	router.Use(jwtauth.Authenticator(tokenAuth))
+	router.Get("/user_banner", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		token  string
+		ip     string
+		status int
+	}{
+		{name: "user", token: alice, ip: "10.0.0.1", status: http.StatusOK},
+		{name: "user again", token: alice, ip: "10.0.0.2", status: http.StatusTooManyRequests},
+		{name: "another user", token: bob, ip: "10.0.0.1", status: http.StatusOK},
+		{name: "without token", ip: "10.0.0.3", status: http.StatusUnauthorized},
+		{name: "without token again", ip: "10.0.0.3", status: http.StatusTooManyRequests},
+		{name: "invalid token", token: "invalid", ip: "10.0.0.3", status: http.StatusTooManyRequests},
+		{name: "invalid token of another ip", token: "invalid", ip: "10.0.0.4", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/user_banner", nil)
+		req.RemoteAddr = tt.ip + ":1234"
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: Retry-After is not set", tt.name)
+		}
+	}
+}
+
+func TestLimitDisabled(t *testing.T) {
+	limiter := New(slog.New(slog.NewTextHandler(io.Discard, nil)), NewMemory())
+	limiter.Set(false, map[string]Limit{ScopeAdmin: {Rate: 0.001, Burst: 1}})
+	handler := limiter.Limit(ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Errorf("request %d: status = %d, RateLimit-Limit = %q", i, rec.Code, rec.Header().Get("RateLimit-Limit"))
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// resource is URL schema is registered under, references to anything else are refused
+const resource = "content.json"
+
+var ErrInvalidSchema = errors.New("invalid content schema")
+
+// FieldError is a single violation, Field is JSON pointer into content ("" is root)
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ViolationsError carries violations of content through storage writes,
+// which are aborted when content does not match feature schema
+type ViolationsError struct {
+	Violations []FieldError
+}
+
+func (e *ViolationsError) Error() string {
+	return fmt.Sprintf("content does not match feature schema: %d violations", len(e.Violations))
+}
+
+type SchemaGetter interface {
+	// GetFeatureContentSchema returns nil if feature has no schema
+	GetFeatureContentSchema(ctx context.Context, featureID int64) ([]byte, error)
+}
+
+// Validator checks banner content against schema of its feature,
+// compiled schemas are kept while their source is unchanged
+type Validator struct {
+	getter SchemaGetter
+
+	mu       sync.Mutex
+	compiled map[int64]compiled
+}
+
+type compiled struct {
+	source []byte
+	schema *jsonschema.Schema
+}
+
+func NewValidator(getter SchemaGetter) *Validator {
+	return &Validator{
+		getter:   getter,
+		compiled: make(map[int64]compiled),
+	}
+}
+
+// Compile parses JSON Schema, remote and file references are not allowed
+func Compile(source []byte) (*jsonschema.Schema, error) {
+	const op = "schema.Compile"
+
+	c := jsonschema.NewCompiler()
+	c.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("reference %q is not allowed", s)
+	}
+	if err := c.AddResource(resource, bytes.NewReader(source)); err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidSchema, err)
+	}
+
+	sch, err := c.Compile(resource)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidSchema, err)
+	}
+
+	return sch, nil
+}
+
+// ValidateContent returns violations of feature schema, none if feature has no schema
+func (v *Validator) ValidateContent(ctx context.Context, featureID int64, content []byte) ([]FieldError, error) {
+	return v.ValidateContentWith(ctx, v.getter, featureID, content)
+}
+
+// ValidateContentWith is ValidateContent with schema read by getter, e.g. in transaction of banner write
+func (v *Validator) ValidateContentWith(ctx context.Context, getter SchemaGetter, featureID int64, content []byte) ([]FieldError, error) {
+	const op = "schema.Validator.ValidateContent"
+
+	source, err := getter.GetFeatureContentSchema(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if source == nil {
+		v.forget(featureID)
+		return nil, nil
+	}
+
+	sch, err := v.schema(featureID, source)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Validate(sch, content), nil
+}
+
+func (v *Validator) schema(featureID int64, source []byte) (*jsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if c, ok := v.compiled[featureID]; ok && bytes.Equal(c.source, source) {
+		return c.schema, nil
+	}
+
+	sch, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	v.compiled[featureID] = compiled{source: source, schema: sch}
+
+	return sch, nil
+}
+
+func (v *Validator) forget(featureID int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.compiled, featureID)
+}
+
+// Validate returns violations of content sorted by field
+func Validate(sch *jsonschema.Schema, content []byte) []FieldError {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return []FieldError{{Field: "", Message: "content is not valid JSON"}}
+	}
+
+	err := sch.Validate(doc)
+	if err == nil {
+		return nil
+	}
+
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return []FieldError{{Field: "", Message: err.Error()}}
+	}
+
+	var errs []FieldError
+	collect(ve, &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+
+	return errs
+}
+
+// collect gathers leaf errors, intermediate ones only say that a subschema failed
+func collect(ve *jsonschema.ValidationError, errs *[]FieldError) {
+	if len(ve.Causes) == 0 {
+		*errs = append(*errs, FieldError{Field: ve.InstanceLocation, Message: ve.Message})
+		return
+	}
+	for _, cause := range ve.Causes {
+		collect(cause, errs)
+	}
+}
@@ -0,0 +1,77 @@
+package seed
+
+import "fmt"
+
+var (
+	sampleTitles = []string{
+		"Free delivery on your first order",
+		"Summer sale: up to 50% off",
+		"Invite a friend and get a bonus",
+		"New collection is here",
+		"Cashback 10% with our card",
+		"Weekend deals on electronics",
+		"Subscribe and save 15%",
+		"Last chance: offer ends tonight",
+	}
+	sampleTexts = []string{
+		"Order today and get it delivered tomorrow at no extra cost.",
+		"Hundreds of items are discounted for a limited time.",
+		"Share your link, both of you get bonus points after the first purchase.",
+		"Discover the latest arrivals picked for you.",
+		"Pay with the card and get part of every purchase back.",
+		"Top brands at the best prices, only this weekend.",
+		"Regular deliveries of your favourites at a lower price.",
+		"Do not miss the best prices of the season.",
+	}
+)
+
+// Generate returns fixture of features feature-1..N and tags tag-1..N as
+// scripts/init.sql used to create, and banners with sample content spread
+// over distinct tag and feature pairs
+func Generate(features, tags, banners int) (*Fixture, error) {
+	if features < 0 || tags < 0 || banners < 0 {
+		return nil, fmt.Errorf("counts must not be negative")
+	}
+	if banners > features*tags {
+		return nil, fmt.Errorf("%d banners need at least as many tag and feature pairs, have %d", banners, features*tags)
+	}
+
+	fixture := &Fixture{
+		Features: make([]Feature, features),
+		Tags:     make([]Tag, tags),
+		Banners:  make([]Banner, banners),
+	}
+	for i := range fixture.Features {
+		fixture.Features[i] = Feature{
+			Name:        fmt.Sprintf("feature-%d", i+1),
+			Description: "sample feature",
+			Owner:       "seed",
+		}
+	}
+	for i := range fixture.Tags {
+		fixture.Tags[i] = Tag{
+			Name:        fmt.Sprintf("tag-%d", i+1),
+			Description: "sample tag",
+			Owner:       "seed",
+		}
+	}
+	for i := range fixture.Banners {
+		// banner i is placed on pair i, so the same counts give the same banners
+		feature, tag := i%features, i/features
+		sample := i % len(sampleTitles)
+		isActive := i%5 != 4
+		fixture.Banners[i] = Banner{
+			Feature: fixture.Features[feature].Name,
+			Tags:    []string{fixture.Tags[tag].Name},
+			Content: map[string]interface{}{
+				"title":     sampleTitles[sample],
+				"text":      sampleTexts[sample],
+				"url":       fmt.Sprintf("https://example.com/promo/%d", i+1),
+				"image_url": fmt.Sprintf("https://example.com/static/banner-%d.png", i+1),
+			},
+			IsActive: &isActive,
+		}
+	}
+
+	return fixture, nil
+}
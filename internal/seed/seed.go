@@ -0,0 +1,286 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/schema"
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is a set of features, tags and banners to create, features and
+// tags are referenced by unique name, e.g.
+//
+//	features:
+//	  - name: checkout
+//	    owner: payments
+//	tags:
+//	  - name: region:EU
+//	  - name: country:DE
+//	    parent: region:EU
+//	banners:
+//	  - feature: checkout
+//	    tags: [country:DE]
+//	    content: {title: Free delivery, url: https://example.com/delivery}
+//	    is_active: true
+type Fixture struct {
+	Features []Feature `yaml:"features"`
+	Tags     []Tag     `yaml:"tags"`
+	Banners  []Banner  `yaml:"banners"`
+}
+
+type Feature struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Owner       string `yaml:"owner"`
+}
+
+type Tag struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Owner       string `yaml:"owner"`
+	// Parent is name of parent tag, it is created before the tag
+	Parent string `yaml:"parent"`
+}
+
+type Banner struct {
+	Feature  string                 `yaml:"feature"`
+	Tags     []string               `yaml:"tags"`
+	Content  map[string]interface{} `yaml:"content"`
+	IsActive *bool                  `yaml:"is_active"`
+}
+
+// Report counts created entities and entities that already existed
+type Report struct {
+	FeaturesCreated int `json:"features_created"`
+	FeaturesExisted int `json:"features_existed"`
+	TagsCreated     int `json:"tags_created"`
+	TagsExisted     int `json:"tags_existed"`
+	BannersCreated  int `json:"banners_created"`
+	BannersExisted  int `json:"banners_existed"`
+}
+
+// Store is a storage backend that can be seeded
+type Store interface {
+	CreateFeature(ctx context.Context, name, description, owner string) (int64, error)
+	GetFeatureIDByName(ctx context.Context, name string) (int64, error)
+	CreateTag(ctx context.Context, name, description, owner string, parentID int64) (int64, error)
+	GetTagIDByName(ctx context.Context, name string) (int64, error)
+	CreateBanner(ctx context.Context, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, time.Time, error)
+}
+
+// ContentValidator checks banner content against content schema of feature
+type ContentValidator interface {
+	ValidateContent(ctx context.Context, featureID int64, content []byte) ([]schema.FieldError, error)
+}
+
+// Seeder creates fixture idempotently: features and tags are matched by name,
+// banners by tag and feature pair, existing ones are left as is
+type Seeder struct {
+	log       *slog.Logger
+	store     Store
+	validator ContentValidator
+}
+
+func New(log *slog.Logger, store Store, validator ContentValidator) *Seeder {
+	return &Seeder{
+		log:       log,
+		store:     store,
+		validator: validator,
+	}
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	const op = "seed.LoadFixture"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+	}
+
+	return &fixture, nil
+}
+
+func (s *Seeder) Run(ctx context.Context, fixture *Fixture) (*Report, error) {
+	const op = "seed.Run"
+
+	log := s.log.With(slog.String("op", op))
+
+	var report Report
+	features := make(map[string]int64, len(fixture.Features))
+	tags := make(map[string]int64, len(fixture.Tags))
+
+	for _, feature := range fixture.Features {
+		id, created, err := ensure(ctx, storage.ErrFeatureExists,
+			func() (int64, error) {
+				return s.store.CreateFeature(ctx, feature.Name, feature.Description, feature.Owner)
+			},
+			func() (int64, error) { return s.store.GetFeatureIDByName(ctx, feature.Name) },
+		)
+		if err != nil {
+			return &report, fmt.Errorf("%s: feature %q: %w", op, feature.Name, err)
+		}
+		features[feature.Name] = id
+		count(&report.FeaturesCreated, &report.FeaturesExisted, created)
+	}
+
+	pending, err := orderTags(fixture.Tags)
+	if err != nil {
+		return &report, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, tag := range pending {
+		parentID, err := s.tagID(ctx, tags, tag.Parent)
+		if err != nil {
+			return &report, fmt.Errorf("%s: parent of tag %q: %w", op, tag.Name, err)
+		}
+		id, created, err := ensure(ctx, storage.ErrTagExists,
+			func() (int64, error) {
+				return s.store.CreateTag(ctx, tag.Name, tag.Description, tag.Owner, parentID)
+			},
+			func() (int64, error) { return s.store.GetTagIDByName(ctx, tag.Name) },
+		)
+		if err != nil {
+			return &report, fmt.Errorf("%s: tag %q: %w", op, tag.Name, err)
+		}
+		tags[tag.Name] = id
+		count(&report.TagsCreated, &report.TagsExisted, created)
+	}
+
+	for i, banner := range fixture.Banners {
+		featureID, ok := features[banner.Feature]
+		if !ok {
+			if featureID, err = s.store.GetFeatureIDByName(ctx, banner.Feature); err != nil {
+				return &report, fmt.Errorf("%s: feature of banner %d: %w", op, i, err)
+			}
+			features[banner.Feature] = featureID
+		}
+
+		tagIDs := make([]int, 0, len(banner.Tags))
+		for _, name := range banner.Tags {
+			tagID, err := s.tagID(ctx, tags, name)
+			if err != nil {
+				return &report, fmt.Errorf("%s: tag of banner %d: %w", op, i, err)
+			}
+			tagIDs = append(tagIDs, int(tagID))
+		}
+
+		content, err := json.Marshal(banner.Content)
+		if err != nil {
+			return &report, fmt.Errorf("%s: content of banner %d: %w", op, i, err)
+		}
+		// seeded banners are held to the same schema as banners of API
+		violations, err := s.validator.ValidateContent(ctx, featureID, content)
+		if err != nil {
+			return &report, fmt.Errorf("%s: content of banner %d: %w", op, i, err)
+		}
+		if len(violations) > 0 {
+			log.Error("content of banner does not match feature schema",
+				slog.Int("banner", i),
+				slog.String("feature", banner.Feature),
+				slog.Any("violations", violations),
+			)
+			return &report, fmt.Errorf("%s: content of banner %d: %w", op, i, &schema.ViolationsError{Violations: violations})
+		}
+		isActive := banner.IsActive == nil || *banner.IsActive
+
+		// any taken pair means banner is already seeded
+		_, _, err = s.store.CreateBanner(ctx, featureID, tagIDs, content, isActive)
+		if errors.Is(err, storage.ErrBannerExists) {
+			report.BannersExisted++
+			continue
+		}
+		if err != nil {
+			return &report, fmt.Errorf("%s: banner %d: %w", op, i, err)
+		}
+		report.BannersCreated++
+	}
+
+	log.Info("storage is seeded", slog.Any("report", report))
+
+	return &report, nil
+}
+
+// tagID returns id of tag by name, empty name is zero id
+func (s *Seeder) tagID(ctx context.Context, tags map[string]int64, name string) (int64, error) {
+	if name == "" {
+		return 0, nil
+	}
+	if id, ok := tags[name]; ok {
+		return id, nil
+	}
+
+	id, err := s.store.GetTagIDByName(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	tags[name] = id
+
+	return id, nil
+}
+
+// ensure creates entity or finds existing one when create reports exists
+func ensure(ctx context.Context, exists error, create, find func() (int64, error)) (int64, bool, error) {
+	id, err := create()
+	if errors.Is(err, exists) {
+		id, err = find()
+		return id, false, err
+	}
+
+	return id, err == nil, err
+}
+
+func count(created, existed *int, isCreated bool) {
+	if isCreated {
+		*created++
+	} else {
+		*existed++
+	}
+}
+
+// orderTags puts parents of fixture before their children
+func orderTags(tags []Tag) ([]Tag, error) {
+	byName := make(map[string]Tag, len(tags))
+	for _, tag := range tags {
+		byName[tag.Name] = tag
+	}
+
+	ordered := make([]Tag, 0, len(tags))
+	state := make(map[string]int, len(tags)) // 1 is visiting, 2 is done
+	var visit func(tag Tag) error
+	visit = func(tag Tag) error {
+		switch state[tag.Name] {
+		case 1:
+			return fmt.Errorf("tag %q is its own ancestor", tag.Name)
+		case 2:
+			return nil
+		}
+		state[tag.Name] = 1
+		if parent, ok := byName[tag.Parent]; ok && tag.Parent != "" {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		state[tag.Name] = 2
+		ordered = append(ordered, tag)
+		return nil
+	}
+
+	for _, tag := range tags {
+		if err := visit(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,113 @@
+package seed
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrderTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []Tag
+		want []string
+	}{
+		{name: "no parents", tags: []Tag{{Name: "a"}, {Name: "b"}}, want: []string{"a", "b"}},
+		{name: "parent first", tags: []Tag{{Name: "eu"}, {Name: "de", Parent: "eu"}}, want: []string{"eu", "de"}},
+		{name: "parent after child", tags: []Tag{{Name: "de", Parent: "eu"}, {Name: "eu"}}, want: []string{"eu", "de"}},
+		{
+			name: "chain in reverse",
+			tags: []Tag{{Name: "berlin", Parent: "de"}, {Name: "de", Parent: "eu"}, {Name: "eu"}},
+			want: []string{"eu", "de", "berlin"},
+		},
+		{name: "parent not in fixture", tags: []Tag{{Name: "de", Parent: "eu"}}, want: []string{"de"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderTags(tt.tags)
+			if err != nil {
+				t.Fatalf("orderTags: %v", err)
+			}
+			got := make([]string, len(ordered))
+			for i, tag := range ordered {
+				got[i] = tag.Name
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderTags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderTagsCycle(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []Tag
+	}{
+		{name: "self", tags: []Tag{{Name: "a", Parent: "a"}}},
+		{name: "pair", tags: []Tag{{Name: "a", Parent: "b"}, {Name: "b", Parent: "a"}}},
+		{name: "loop", tags: []Tag{{Name: "a", Parent: "c"}, {Name: "b", Parent: "a"}, {Name: "c", Parent: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := orderTags(tt.tags)
+			if err == nil || !strings.Contains(err.Error(), "is its own ancestor") {
+				t.Errorf("orderTags error = %v, want own ancestor error", err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name                    string
+		features, tags, banners int
+		wantErr                 bool
+	}{
+		{name: "empty", features: 0, tags: 0, banners: 0},
+		{name: "no banners", features: 3, tags: 2, banners: 0},
+		{name: "some pairs", features: 3, tags: 2, banners: 4},
+		{name: "all pairs", features: 3, tags: 2, banners: 6},
+		{name: "more banners than pairs", features: 3, tags: 2, banners: 7, wantErr: true},
+		{name: "banners without features", features: 0, tags: 2, banners: 1, wantErr: true},
+		{name: "negative count", features: -1, tags: 2, banners: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture, err := Generate(tt.features, tt.tags, tt.banners)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Generate error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if len(fixture.Features) != tt.features || len(fixture.Tags) != tt.tags || len(fixture.Banners) != tt.banners {
+				t.Fatalf("Generate counts = %d, %d, %d, want %d, %d, %d",
+					len(fixture.Features), len(fixture.Tags), len(fixture.Banners), tt.features, tt.tags, tt.banners)
+			}
+
+			pairs := make(map[[2]string]bool, len(fixture.Banners))
+			for i, banner := range fixture.Banners {
+				if len(banner.Tags) != 1 {
+					t.Fatalf("banner %d tags = %v, want one tag", i, banner.Tags)
+				}
+				pair := [2]string{banner.Tags[0], banner.Feature}
+				if pairs[pair] {
+					t.Errorf("banner %d reuses tag and feature pair %v", i, pair)
+				}
+				pairs[pair] = true
+			}
+
+			again, err := Generate(tt.features, tt.tags, tt.banners)
+			if err != nil {
+				t.Fatalf("Generate again: %v", err)
+			}
+			if !reflect.DeepEqual(fixture, again) {
+				t.Errorf("Generate is not deterministic for the same counts")
+			}
+		})
+	}
+}
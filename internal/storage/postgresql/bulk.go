@@ -0,0 +1,121 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+const (
+	BulkStatusOK         = "ok"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+	BulkStatusSkipped    = "skipped"
+)
+
+// BannerOp is a single operation of bulk request, fields have the same meaning
+// as arguments of CreateBanner, UpdateBanner and DeleteBanner
+type BannerOp struct {
+	Op        string
+	BannerID  int64
+	FeatureID int64
+	TagIDs    []int
+	Content   json.RawMessage
+	IsActive  *bool
+	IfMatch   []int64
+}
+
+type BannerOpResult struct {
+	Status   string
+	BannerID int64
+	Version  int64
+	Err      error
+}
+
+// BulkBanners applies ops in order. Atomic run is a single transaction which is
+// rolled back on the first failure, later ops are skipped; otherwise every op
+// is committed on its own and failures don't affect other ops
+func (s *Storage) BulkBanners(ctx context.Context, ops []BannerOp, atomic bool, check ContentCheck) ([]BannerOpResult, error) {
+	const op = "storage.postgresql.BulkBanners"
+
+	results := make([]BannerOpResult, len(ops))
+
+	if !atomic {
+		for i := range ops {
+			err := s.inTx(ctx, func(tx *sql.Tx) error {
+				return applyBannerOp(ctx, tx, ops[i], &results[i], check)
+			})
+			if err != nil {
+				results[i] = BannerOpResult{Status: BulkStatusFailed, BannerID: ops[i].BannerID, Err: err}
+				continue
+			}
+			results[i].Status = BulkStatusOK
+		}
+
+		return results, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to start transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for i := range ops {
+		if err := applyBannerOp(ctx, tx, ops[i], &results[i], check); err != nil {
+			// nothing is committed, so created banners don't exist and versions are not changed
+			for j := 0; j < i; j++ {
+				results[j] = BannerOpResult{Status: BulkStatusRolledBack, BannerID: ops[j].BannerID}
+			}
+			results[i] = BannerOpResult{Status: BulkStatusFailed, BannerID: ops[i].BannerID, Err: err}
+			for j := i + 1; j < len(ops); j++ {
+				results[j] = BannerOpResult{Status: BulkStatusSkipped, BannerID: ops[j].BannerID}
+			}
+			return results, nil
+		}
+		results[i].Status = BulkStatusOK
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+	}
+
+	return results, nil
+}
+
+func applyBannerOp(ctx context.Context, tx *sql.Tx, op BannerOp, result *BannerOpResult, check ContentCheck) error {
+	var err error
+
+	switch op.Op {
+	case BulkCreate:
+		isActive := op.IsActive != nil && *op.IsActive
+		result.BannerID, _, err = createBanner(ctx, tx, op.FeatureID, op.TagIDs, op.Content, isActive, check)
+		result.Version = 1
+	case BulkUpdate:
+		var content, isActive interface{}
+		if op.Content != nil {
+			content = op.Content
+		}
+		if op.IsActive != nil {
+			isActive = *op.IsActive
+		}
+		result.BannerID = op.BannerID
+		result.Version, _, err = updateBanner(ctx, tx, op.BannerID, op.FeatureID, op.TagIDs, content, isActive, op.IfMatch, check)
+	case BulkDelete:
+		result.BannerID = op.BannerID
+		_, err = deleteBanner(ctx, tx, op.BannerID, op.IfMatch)
+	default:
+		err = fmt.Errorf("unknown operation %q: %w", op.Op, storage.ErrBannerInvalidData)
+	}
+
+	return err
+}
@@ -0,0 +1,127 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Options struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	DB       string
+
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	StatementTimeout time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaDSN is optional read replica connection string (keyword/value or URL)
+	ReplicaDSN string
+
+	// SearchFields are top-level content keys indexed for full-text search
+	SearchFields []string
+	// SearchLanguage is text search configuration, e.g. simple or russian
+	SearchLanguage string
+}
+
+// dsn builds keyword/value connection string for primary
+func (o Options) dsn() string {
+	sslMode := o.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	params := []string{
+		param("host", o.Host),
+		param("port", fmt.Sprint(o.Port)),
+		param("user", o.User),
+		param("password", o.Password),
+		param("dbname", o.DB),
+		param("sslmode", sslMode),
+	}
+	if o.SSLRootCert != "" {
+		params = append(params, param("sslrootcert", o.SSLRootCert))
+	}
+	if o.SSLCert != "" {
+		params = append(params, param("sslcert", o.SSLCert))
+	}
+	if o.SSLKey != "" {
+		params = append(params, param("sslkey", o.SSLKey))
+	}
+
+	return o.withStatementTimeout(strings.Join(params, " "))
+}
+
+// replicaDSN converts replica DSN to keyword/value form and applies statement timeout
+func (o Options) replicaDSN() (string, error) {
+	dsn := o.ReplicaDSN
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		dsn, err = pq.ParseURL(dsn)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return o.withStatementTimeout(dsn), nil
+}
+
+// withStatementTimeout appends server side statement_timeout, later keys override earlier ones
+func (o Options) withStatementTimeout(dsn string) string {
+	if o.StatementTimeout <= 0 {
+		return dsn
+	}
+
+	return dsn + " " + param("options", fmt.Sprintf("-c statement_timeout=%d", o.StatementTimeout.Milliseconds()))
+}
+
+// param quotes value for keyword/value connection string
+func param(key, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+
+	return fmt.Sprintf("%s='%s'", key, value)
+}
+
+func open(dsn string, opts Options) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// read runs query on replica if configured, falling back to primary on replica failure
+func (s *Storage) read(ctx context.Context, query func(db *sql.DB) error) error {
+	if s.replica == nil {
+		return query(s.db)
+	}
+
+	err := query(s.replica)
+	if err == nil || errors.Is(err, sql.ErrNoRows) || ctx.Err() != nil {
+		return err
+	}
+
+	return query(s.db)
+}
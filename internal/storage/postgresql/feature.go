@@ -0,0 +1,224 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+type Feature struct {
+	FeatureID       int64           `json:"feature_id"`
+	DefaultBannerID *int64          `json:"default_banner_id"`
+	ContentSchema   json.RawMessage `json:"content_schema,omitempty"`
+	Meta
+}
+
+const featureActiveBannersQuery = `
+	SELECT EXISTS (SELECT 1 FROM banner WHERE feature_id = $1 AND is_active)
+`
+
+func (s *Storage) CreateFeature(ctx context.Context, name, description, owner string) (int64, error) {
+	const op = "storage.postgresql.CreateFeature"
+
+	id, err := s.createMeta(ctx, "feature", featureErrors, name, description, owner)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetFeature(ctx context.Context, featureID int64) (*Feature, error) {
+	const op = "storage.postgresql.GetFeature"
+
+	var feature Feature
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, default_banner_id, content_schema, `+metaColumns+`
+		FROM feature
+		WHERE id = $1;
+	`, featureID).Scan(&feature.FeatureID, &feature.DefaultBannerID, &feature.ContentSchema, &feature.Name, &feature.Description, &feature.Owner, &feature.Archived, &feature.CreatedAT, &feature.UpdatedAT)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrFeatureNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get feature: %w", op, err)
+	}
+
+	return &feature, nil
+}
+
+// GetFeatureIDByName returns id of feature with unique name
+func (s *Storage) GetFeatureIDByName(ctx context.Context, name string) (int64, error) {
+	const op = "storage.postgresql.GetFeatureIDByName"
+
+	id, err := s.findMeta(ctx, "feature", featureErrors, name)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetFeatureList returns features ordered by id, archived ones only if includeArchived
+func (s *Storage) GetFeatureList(ctx context.Context, includeArchived bool, limit, offset int64) ([]*Feature, error) {
+	const op = "storage.postgresql.GetFeatureList"
+
+	query := `SELECT id, default_banner_id, content_schema, ` + metaColumns + ` FROM feature `
+	if !includeArchived {
+		query += `WHERE NOT archived `
+	}
+	query += `ORDER BY id LIMIT NULLIF($1, 0) OFFSET $2;`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute query: %w", op, err)
+	}
+	defer rows.Close()
+
+	features := make([]*Feature, 0)
+	for rows.Next() {
+		var feature Feature
+		if err := rows.Scan(&feature.FeatureID, &feature.DefaultBannerID, &feature.ContentSchema, &feature.Name, &feature.Description, &feature.Owner, &feature.Archived, &feature.CreatedAT, &feature.UpdatedAT); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan rows: %w", op, err)
+		}
+		features = append(features, &feature)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: error during iteration: %w", op, err)
+	}
+
+	return features, nil
+}
+
+// UpdateFeature refuses to archive feature with active banners unless force is set
+func (s *Storage) UpdateFeature(ctx context.Context, featureID int64, upd MetaUpdate, force bool) error {
+	const op = "storage.postgresql.UpdateFeature"
+
+	if err := s.updateMeta(ctx, "feature", featureErrors, featureID, upd, featureActiveBannersQuery, force, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteFeature(ctx context.Context, featureID int64) error {
+	const op = "storage.postgresql.DeleteFeature"
+
+	if err := s.deleteMeta(ctx, "feature", featureErrors, featureID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetFeatureDefaultBanner sets banner served for tags without own banner,
+// the banner must belong to the feature, zero bannerID removes default
+func (s *Storage) SetFeatureDefaultBanner(ctx context.Context, featureID, bannerID int64) error {
+	const op = "storage.postgresql.SetFeatureDefaultBanner"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: failed to start transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM feature WHERE id = $1 FOR UPDATE`, featureID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", op, storage.ErrFeatureNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to get feature: %w", op, err)
+	}
+
+	if bannerID != 0 {
+		var bannerFeatureID int64
+		err = tx.QueryRowContext(ctx, `SELECT feature_id FROM banner WHERE id = $1`, bannerID).Scan(&bannerFeatureID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrBannerNotExists)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to get banner: %w", op, err)
+		}
+		if bannerFeatureID != featureID {
+			return fmt.Errorf("%s: banner of another feature: %w", op, storage.ErrBannerInvalidData)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE feature SET default_banner_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, featureID, nullID(bannerID))
+	if err != nil {
+		return fmt.Errorf("%s: failed to set default banner: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetFeatureContentSchema returns JSON Schema of banner content, nil if feature
+// has no schema or does not exist (banner storage rejects unknown features)
+func (s *Storage) GetFeatureContentSchema(ctx context.Context, featureID int64) ([]byte, error) {
+	const op = "storage.postgresql.GetFeatureContentSchema"
+
+	schema, err := featureContentSchema(s.db.QueryRowContext(ctx, `SELECT content_schema FROM feature WHERE id = $1`, featureID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schema, nil
+}
+
+// txSchemas reads content schemas in transaction of banner write,
+// so check of content doesn't take another connection of the pool
+type txSchemas struct {
+	tx *sql.Tx
+}
+
+func (t txSchemas) GetFeatureContentSchema(ctx context.Context, featureID int64) ([]byte, error) {
+	return featureContentSchema(t.tx.QueryRowContext(ctx, `SELECT content_schema FROM feature WHERE id = $1`, featureID))
+}
+
+func featureContentSchema(row *sql.Row) ([]byte, error) {
+	var schema []byte
+	err := row.Scan(&schema)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// SetFeatureContentSchema sets JSON Schema of banner content, nil schema removes it,
+// existing banners are not revalidated
+func (s *Storage) SetFeatureContentSchema(ctx context.Context, featureID int64, schema []byte) error {
+	const op = "storage.postgresql.SetFeatureContentSchema"
+
+	value := sql.NullString{String: string(schema), Valid: schema != nil}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE feature SET content_schema = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+	`, featureID, value)
+	if err != nil {
+		return fmt.Errorf("%s: failed to set content schema: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrFeatureNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package postgresql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+// idempotencyLease is how long reservation of running request is held,
+// after it retry takes over key of request that never completed
+const idempotencyLease = 30 * time.Second
+
+// ReserveIdempotencyKey claims key for request with fingerprint until ttl expires,
+// it returns owner token of reservation to complete or release key with.
+// It returns stored response if request was already completed, ErrIdempotencyMismatch
+// if key was used with another request and ErrIdempotencyInProgress if it is running
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (string, *storage.IdempotentResponse, error) {
+	const op = "storage.postgresql.ReserveIdempotencyKey"
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return "", nil, fmt.Errorf("%s: failed to delete expired keys: %w", op, err)
+	}
+
+	owner, err := newIdempotencyOwner()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// reservation of the same request with expired lease is taken over by new owner
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_key(key, fingerprint, expires_at, locked_until, owner)
+		VALUES($1, $2, CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond', CURRENT_TIMESTAMP + $4 * INTERVAL '1 millisecond', $5)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at, locked_until = EXCLUDED.locked_until, owner = EXCLUDED.owner
+		WHERE idempotency_key.status = 0
+			AND idempotency_key.fingerprint = EXCLUDED.fingerprint
+			AND idempotency_key.locked_until < CURRENT_TIMESTAMP
+	`, key, fingerprint, ttl.Milliseconds(), idempotencyLease.Milliseconds(), owner)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to reserve key: %w", op, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if rowsAffected == 1 {
+		return owner, nil, nil
+	}
+
+	var (
+		storedFingerprint string
+		response          storage.IdempotentResponse
+	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status, COALESCE(response, '') FROM idempotency_key WHERE key = $1
+	`, key).Scan(&storedFingerprint, &response.Status, &response.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to get key: %w", op, err)
+	}
+	if storedFingerprint != fingerprint {
+		return "", nil, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyMismatch)
+	}
+	if response.Status == 0 {
+		return "", nil, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyInProgress)
+	}
+
+	return "", &response, nil
+}
+
+// newIdempotencyOwner generates token of reservation, so request whose lease
+// expired can't complete or release key taken over by retry
+func newIdempotencyOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate owner: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// CreateBannerIdempotent creates banner as CreateBanner does and stores response built by respond
+// for key reserved by owner in the same transaction, so banner once committed is never created again by retry
+func (s *Storage) CreateBannerIdempotent(ctx context.Context, key, owner string, featureID int64, tagIDs []int, content []byte, isActive bool, respond func(bannerID int64) (*storage.IdempotentResponse, error)) (int64, time.Time, error) {
+	const op = "storage.postgresql.CreateBannerIdempotent"
+
+	var (
+		bannerID  int64
+		createdAt time.Time
+	)
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		bannerID, createdAt, err = createBanner(ctx, tx, featureID, tagIDs, content, isActive, nil)
+		if err != nil {
+			return err
+		}
+
+		response, err := respond(bannerID)
+		if err != nil {
+			return err
+		}
+
+		return completeIdempotencyKey(ctx, tx, key, owner, response)
+	})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bannerID, createdAt, nil
+}
+
+// completeIdempotencyKey stores response of request for replays, it fails if key
+// was taken over by another owner meanwhile
+func completeIdempotencyKey(ctx context.Context, tx *sql.Tx, key, owner string, response *storage.IdempotentResponse) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE idempotency_key SET status = $3, response = $4 WHERE key = $1 AND owner = $2 AND status = 0
+	`, key, owner, response.Status, response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to store response: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrIdempotencyInProgress
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets key of failed request reserved by owner, so it can be retried;
+// key of completed request or taken over by another owner is kept
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key, owner string) error {
+	const op = "storage.postgresql.ReleaseIdempotencyKey"
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE key = $1 AND owner = $2 AND status = 0`, key, owner); err != nil {
+		return fmt.Errorf("%s: failed to release key: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/lib/pq"
+)
+
+// BannerFilter holds optional filters of banner list, empty fields are not applied
+type BannerFilter struct {
+	FeatureIDs  []int64
+	TagIDs      []int64
+	IsActive    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// SortBy is one of id, created_at, updated_at
+	SortBy   string
+	SortDesc bool
+	Limit    int64
+	// Offset is ignored when Cursor is set
+	Offset int64
+	Cursor *BannerCursor
+}
+
+const (
+	SortByID        = "id"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+)
+
+// BannerCursor points at a banner of the list for keyset pagination,
+// Backward cursor selects the page before the banner
+type BannerCursor struct {
+	SortBy   string `json:"s"`
+	SortDesc bool   `json:"d"`
+	Value    string `json:"v,omitempty"`
+	ID       int64  `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// BannerPage is a page of banner list, cursors are empty at list edges
+type BannerPage struct {
+	Banners    []*Banner
+	Total      int64
+	NextCursor string
+	PrevCursor string
+}
+
+// Encode returns opaque cursor token
+func (c BannerCursor) Encode() string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeBannerCursor(token string) (*BannerCursor, error) {
+	const op = "storage.postgresql.DecodeBannerCursor"
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+	}
+
+	var cursor BannerCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+	}
+
+	return &cursor, nil
+}
+
+func (f *BannerFilter) sortBy() string {
+	if f.SortBy == "" {
+		return SortByID
+	}
+
+	return f.SortBy
+}
+
+// cursorAt returns cursor pointing at banner in current sort
+func (f *BannerFilter) cursorAt(banner *Banner, backward bool) string {
+	cursor := BannerCursor{
+		SortBy:   f.sortBy(),
+		SortDesc: f.SortDesc,
+		ID:       banner.BannerID,
+		Backward: backward,
+	}
+	switch cursor.SortBy {
+	case SortByCreatedAt:
+		cursor.Value = banner.CreatedAT
+	case SortByUpdatedAt:
+		cursor.Value = banner.UpdatedAT
+	}
+
+	return cursor.Encode()
+}
+
+// where builds WHERE clause for filter, arguments are numbered from 1
+func (f *BannerFilter) where() (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if len(f.FeatureIDs) > 0 {
+		add("b.feature_id = ANY($%d)", pq.Array(f.FeatureIDs))
+	}
+	if len(f.TagIDs) > 0 {
+		add("EXISTS (SELECT 1 FROM banner_tag bt WHERE bt.banner_id = b.id AND bt.tag_id = ANY($%d))", pq.Array(f.TagIDs))
+	}
+	if f.IsActive != nil {
+		add("b.is_active = $%d", *f.IsActive)
+	}
+	if f.CreatedFrom != nil {
+		add("b.created_at >= $%d", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		add("b.created_at < $%d", *f.CreatedTo)
+	}
+	if f.UpdatedFrom != nil {
+		add("b.updated_at >= $%d", *f.UpdatedFrom)
+	}
+	if f.UpdatedTo != nil {
+		add("b.updated_at < $%d", *f.UpdatedTo)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// keyset returns condition selecting banners after (or before) cursor
+func (f *BannerFilter) keyset(argN int) (string, []interface{}) {
+	c := f.Cursor
+
+	cmp := ">"
+	if c.SortDesc != c.Backward {
+		cmp = "<"
+	}
+
+	if c.SortBy == SortByID {
+		return fmt.Sprintf("b.id %s $%d", cmp, argN), []interface{}{c.ID}
+	}
+
+	return fmt.Sprintf("(b.%s, b.id) %s ($%d::timestamp, $%d)", c.SortBy, cmp, argN, argN+1), []interface{}{c.Value, c.ID}
+}
+
+// orderBy returns ORDER BY clause, id is a tie breaker for stable pages
+func (f *BannerFilter) orderBy() (string, error) {
+	desc := f.SortDesc
+	// backward page is read in reverse order and flipped afterwards
+	if f.Cursor != nil && f.Cursor.Backward {
+		desc = !desc
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	switch f.sortBy() {
+	case SortByID:
+		return fmt.Sprintf("ORDER BY b.id %s", direction), nil
+	case SortByCreatedAt, SortByUpdatedAt:
+		return fmt.Sprintf("ORDER BY b.%s %s, b.id %s", f.SortBy, direction, direction), nil
+	default:
+		return "", storage.ErrBannerInvalidData
+	}
+}
+
+// GetBannerList returns page of banners matching filter in requested order
+// and total number of matching banners, limit applies to banners, not tags
+func (s *Storage) GetBannerList(ctx context.Context, filter BannerFilter) (*BannerPage, error) {
+	const op = "storage.postgresql.GetBannerList"
+
+	if c := filter.Cursor; c != nil && (c.SortBy != filter.sortBy() || c.SortDesc != filter.SortDesc) {
+		return nil, fmt.Errorf("%s: cursor of another sort: %w", op, storage.ErrBannerInvalidData)
+	}
+
+	where, args := filter.where()
+	orderBy, err := filter.orderBy()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pageWhere := where
+	pageArgs := append([]interface{}{}, args...)
+	offset := filter.Offset
+	if filter.Cursor != nil {
+		cond, condArgs := filter.keyset(len(pageArgs) + 1)
+		pageArgs = append(pageArgs, condArgs...)
+		if pageWhere == "" {
+			pageWhere = "WHERE " + cond
+		} else {
+			pageWhere += " AND " + cond
+		}
+		offset = 0
+	}
+
+	// one extra banner tells whether there is a page after this one
+	limit := filter.Limit
+	if limit > 0 {
+		limit++
+	}
+	pageArgs = append(pageArgs, limit, offset)
+
+	query := `
+		SELECT
+		b.id,
+		b.content,
+		COALESCE(b.is_active, false),
+		b.feature_id,
+		b.created_at,
+		b.updated_at,
+		ARRAY(SELECT bt.tag_id FROM banner_tag bt WHERE bt.banner_id = b.id ORDER BY bt.tag_id),
+		b.version
+		FROM
+		banner b
+	` + pageWhere + " " + orderBy +
+		fmt.Sprintf(" LIMIT NULLIF($%d, 0) OFFSET $%d;", len(pageArgs)-1, len(pageArgs))
+
+	// get banner list
+	var page BannerPage
+	err = s.read(ctx, func(db *sql.DB) error {
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM banner b `+where, args...).Scan(&page.Total); err != nil {
+			return err
+		}
+
+		rows, err := db.QueryContext(ctx, query, pageArgs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		page.Banners = make([]*Banner, 0)
+		for rows.Next() {
+			var banner Banner
+			// read the lines from the query result and add them to the list
+			if err := rows.Scan(&banner.BannerID, &banner.Content, &banner.IsActive, &banner.FeatureID, &banner.CreatedAT, &banner.UpdatedAT, pq.Array(&banner.TagIDs), &banner.Version); err != nil {
+				return err
+			}
+			page.Banners = append(page.Banners, &banner)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get banner list: %w", op, err)
+	}
+
+	hasMore := filter.Limit > 0 && int64(len(page.Banners)) > filter.Limit
+	if hasMore {
+		page.Banners = page.Banners[:filter.Limit]
+	}
+	if len(page.Banners) == 0 {
+		return &page, nil
+	}
+
+	first, last := page.Banners[0], page.Banners[len(page.Banners)-1]
+	if filter.Cursor != nil && filter.Cursor.Backward {
+		slices.Reverse(page.Banners)
+		first, last = last, first
+
+		// came from the next page, so it exists
+		page.NextCursor = filter.cursorAt(last, false)
+		if hasMore {
+			page.PrevCursor = filter.cursorAt(first, true)
+		}
+
+		return &page, nil
+	}
+
+	if hasMore {
+		page.NextCursor = filter.cursorAt(last, false)
+	}
+	if filter.Cursor != nil || filter.Offset > 0 {
+		page.PrevCursor = filter.cursorAt(first, true)
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,106 @@
+package postgresql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+func TestBannerCursorEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor BannerCursor
+	}{
+		{name: "id", cursor: BannerCursor{SortBy: SortByID, ID: 42}},
+		{name: "id desc backward", cursor: BannerCursor{SortBy: SortByID, SortDesc: true, ID: 7, Backward: true}},
+		{name: "created_at", cursor: BannerCursor{SortBy: SortByCreatedAt, Value: "2024-04-10T12:00:00.123456Z", ID: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeBannerCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodeBannerCursor: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.cursor) {
+				t.Errorf("DecodeBannerCursor = %#v, want %#v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeBannerCursorInvalid(t *testing.T) {
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "W10"} {
+		t.Run(token, func(t *testing.T) {
+			if _, err := DecodeBannerCursor(token); !errors.Is(err, storage.ErrBannerInvalidData) {
+				t.Errorf("DecodeBannerCursor error = %v, want ErrBannerInvalidData", err)
+			}
+		})
+	}
+}
+
+func TestBannerFilterKeyset(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor BannerCursor
+		argN   int
+		cond   string
+		args   []interface{}
+	}{
+		{
+			name:   "id forward",
+			cursor: BannerCursor{SortBy: SortByID, ID: 10},
+			argN:   1,
+			cond:   "b.id > $1",
+			args:   []interface{}{int64(10)},
+		},
+		{
+			name:   "id backward",
+			cursor: BannerCursor{SortBy: SortByID, ID: 10, Backward: true},
+			argN:   1,
+			cond:   "b.id < $1",
+			args:   []interface{}{int64(10)},
+		},
+		{
+			name:   "id desc forward",
+			cursor: BannerCursor{SortBy: SortByID, SortDesc: true, ID: 10},
+			argN:   3,
+			cond:   "b.id < $3",
+			args:   []interface{}{int64(10)},
+		},
+		{
+			name:   "id desc backward",
+			cursor: BannerCursor{SortBy: SortByID, SortDesc: true, ID: 10, Backward: true},
+			argN:   3,
+			cond:   "b.id > $3",
+			args:   []interface{}{int64(10)},
+		},
+		{
+			name:   "updated_at forward",
+			cursor: BannerCursor{SortBy: SortByUpdatedAt, Value: "2024-04-10T12:00:00Z", ID: 10},
+			argN:   2,
+			cond:   "(b.updated_at, b.id) > ($2::timestamp, $3)",
+			args:   []interface{}{"2024-04-10T12:00:00Z", int64(10)},
+		},
+		{
+			name:   "created_at desc forward",
+			cursor: BannerCursor{SortBy: SortByCreatedAt, SortDesc: true, Value: "2024-04-10T12:00:00Z", ID: 10},
+			argN:   1,
+			cond:   "(b.created_at, b.id) < ($1::timestamp, $2)",
+			args:   []interface{}{"2024-04-10T12:00:00Z", int64(10)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := BannerFilter{SortBy: tt.cursor.SortBy, SortDesc: tt.cursor.SortDesc, Cursor: &tt.cursor}
+			cond, args := filter.keyset(tt.argN)
+			if cond != tt.cond {
+				t.Errorf("keyset = %q, want %q", cond, tt.cond)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("keyset args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
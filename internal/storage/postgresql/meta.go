@@ -0,0 +1,212 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/lib/pq"
+)
+
+// Meta is descriptive data shared by feature and tag
+type Meta struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	Archived    bool   `json:"archived"`
+	CreatedAT   string `json:"created_at"`
+	UpdatedAT   string `json:"updated_at"`
+}
+
+// MetaUpdate holds optional fields of PATCH, nil fields are left as is
+type MetaUpdate struct {
+	Name        *string
+	Description *string
+	Owner       *string
+	Archived    *bool
+}
+
+// metaErrors maps generic failures to errors of a concrete entity
+type metaErrors struct {
+	notFound         error
+	exists           error
+	inUse            error
+	hasActiveBanners error
+	invalidRef       error
+}
+
+// column is an entity specific column set along with metadata
+type column struct {
+	name  string
+	value interface{}
+}
+
+var (
+	featureErrors = metaErrors{
+		notFound:         storage.ErrFeatureNotFound,
+		exists:           storage.ErrFeatureExists,
+		inUse:            storage.ErrFeatureInUse,
+		hasActiveBanners: storage.ErrFeatureHasActiveBanners,
+		invalidRef:       storage.ErrBannerInvalidData,
+	}
+	tagErrors = metaErrors{
+		notFound:         storage.ErrTagNotFound,
+		exists:           storage.ErrTagExists,
+		inUse:            storage.ErrTagInUse,
+		hasActiveBanners: storage.ErrTagHasActiveBanners,
+		invalidRef:       storage.ErrTagInvalidParent,
+	}
+)
+
+const metaColumns = `COALESCE(name, ''), description, owner, archived, created_at, updated_at`
+
+func (s *Storage) createMeta(ctx context.Context, table string, errs metaErrors, name, description, owner string, extra ...column) (int64, error) {
+	if strings.TrimSpace(name) == "" {
+		return 0, storage.ErrBannerInvalidData
+	}
+
+	columns := []string{"name", "description", "owner"}
+	args := []interface{}{name, description, owner}
+	for _, c := range extra {
+		columns = append(columns, c.name)
+		args = append(args, c.value)
+	}
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s(%s) VALUES(%s) RETURNING id
+	`, table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")), args...).Scan(&id)
+	if err != nil {
+		return 0, errs.mapPQ(err)
+	}
+
+	return id, nil
+}
+
+// mapPQ converts constraint violations to entity errors
+func (errs metaErrors) mapPQ(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return errs.exists
+		case "foreign_key_violation":
+			return errs.invalidRef
+		}
+	}
+
+	return err
+}
+
+// updateMeta applies upd and extra columns, archiving is refused while
+// activeBannersQuery finds active banners unless force is set, check
+// (if any) validates update inside the transaction
+func (s *Storage) updateMeta(ctx context.Context, table string, errs metaErrors, id int64, upd MetaUpdate, activeBannersQuery string, force bool, check func(tx *sql.Tx) error, extra ...column) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT archived FROM %s WHERE id = $1 FOR UPDATE`, table), id).Scan(&archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.notFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if upd.Archived != nil && *upd.Archived && !archived && !force {
+		var hasActive bool
+		if err := tx.QueryRowContext(ctx, activeBannersQuery, id).Scan(&hasActive); err != nil {
+			return err
+		}
+		if hasActive {
+			return errs.hasActiveBanners
+		}
+	}
+
+	if check != nil {
+		if err := check(tx); err != nil {
+			return err
+		}
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET updated_at = CURRENT_TIMESTAMP`, table)
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(", %s = $%d", column, len(args))
+	}
+	if upd.Name != nil {
+		if strings.TrimSpace(*upd.Name) == "" {
+			return storage.ErrBannerInvalidData
+		}
+		set("name", *upd.Name)
+	}
+	if upd.Description != nil {
+		set("description", *upd.Description)
+	}
+	if upd.Owner != nil {
+		set("owner", *upd.Owner)
+	}
+	if upd.Archived != nil {
+		set("archived", *upd.Archived)
+	}
+	for _, c := range extra {
+		set(c.name, c.value)
+	}
+	args = append(args, id)
+	query += fmt.Sprintf(" WHERE id = $%d", len(args))
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errs.mapPQ(err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) findMeta(ctx context.Context, table string, errs metaErrors, name string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE name = $1`, table), name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errs.notFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) deleteMeta(ctx context.Context, table string, errs metaErrors, id int64) error {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table), id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			return errs.inUse
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errs.notFound
+	}
+
+	return nil
+}
+
+// nullID stores zero id as NULL
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
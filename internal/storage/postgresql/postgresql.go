@@ -4,38 +4,56 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
+	"github.com/JustForWorld/banner-shift/internal/http-server/etag"
+	"github.com/JustForWorld/banner-shift/internal/schema"
 	"github.com/JustForWorld/banner-shift/internal/storage"
-	"github.com/JustForWorld/banner-shift/internal/storage/redis"
 	"github.com/lib/pq"
 )
 
 type Storage struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB
+	search  searchConfig
 }
 
 type Banner struct {
-	BannerID  int64       `json:"banner_id"`
-	TagIDs    []int64     `json:"tag_ids"`
-	FeatureID int64       `json:"feature_id"`
-	Content   interface{} `json:"content"`
-	IsActive  string      `json:"is_active"`
-	CreatedAT string      `json:"created_at"`
-	UpdatedAT string      `json:"updated_at"`
+	BannerID  int64           `json:"banner_id"`
+	TagIDs    []int64         `json:"tag_ids"`
+	FeatureID int64           `json:"feature_id"`
+	Content   json.RawMessage `json:"content"`
+	IsActive  bool            `json:"is_active"`
+	CreatedAT string          `json:"created_at"`
+	UpdatedAT string          `json:"updated_at"`
+	// Version is incremented on every update, it is exposed as ETag
+	Version int64 `json:"version"`
 }
 
-func New(ctx context.Context, user, password, host, dbname string, port int) (*Storage, error) {
+func New(ctx context.Context, opts Options) (*Storage, error) {
 	const op = "storage.postgresql.New"
 
-	psqlconn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-
-	db, err := sql.Open("postgres", psqlconn)
+	db, err := open(opts.dsn(), opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// replica is optional, reads fall back to primary while it is unavailable
+	var replica *sql.DB
+	if opts.ReplicaDSN != "" {
+		dsn, err := opts.replicaDSN()
+		if err != nil {
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+
+		replica, err = open(dsn, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+	}
+
 	// create banner table
 	stmtBanner, err := db.PrepareContext(ctx, `
 		CREATE TABLE IF NOT EXISTS banner (
@@ -156,232 +174,410 @@ func New(ctx context.Context, user, password, host, dbname string, port int) (*S
 		return nil, fmt.Errorf("exec UNIQUE CONSTAINT with tag_id and feature_id: %s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
-}
+	// add name and metadata to feature and tag tables
+	for _, table := range []string{"feature", "tag"} {
+		_, err = db.ExecContext(ctx, fmt.Sprintf(`
+			ALTER TABLE %[1]s
+				ADD COLUMN IF NOT EXISTS name TEXT,
+				ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+				ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '',
+				ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT false,
+				ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+			CREATE UNIQUE INDEX IF NOT EXISTS %[1]s_name_key ON %[1]s (name);
+		`, table))
+		if err != nil {
+			return nil, fmt.Errorf("exec %s metadata: %s: %w", table, op, err)
+		}
+	}
 
-func (s *Storage) CreateBanner(ctx context.Context, redis *redis.Storage, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, error) {
-	const op = "storage.postgresql.CreateBanner"
+	// tag hierarchy
+	_, err = db.ExecContext(ctx, `
+		ALTER TABLE tag ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES tag (id) ON DELETE SET NULL;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("exec tag hierarchy: %s: %w", op, err)
+	}
 
-	// checking required fields
-	if featureID == 0 || content == nil || len(tagIDs) == 0 {
-		return 0, fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+	// default banner of feature
+	_, err = db.ExecContext(ctx, `
+		ALTER TABLE feature ADD COLUMN IF NOT EXISTS default_banner_id INTEGER REFERENCES banner (id) ON DELETE SET NULL;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("exec feature default banner: %s: %w", op, err)
 	}
 
-	tx, err := s.db.Begin()
+	// banner version for optimistic concurrency
+	_, err = db.ExecContext(ctx, `
+		ALTER TABLE banner ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+	`)
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to start transaction: %w", op, err)
+		return nil, fmt.Errorf("exec banner version: %s: %w", op, err)
 	}
-	defer tx.Rollback()
 
-	// insert new banner
-	stmtNewBanner, err := s.db.PrepareContext(ctx, `
-		INSERT INTO banner(content, is_active, feature_id) VALUES($1, $2, $3) RETURNING id
+	// optional JSON Schema of banner content
+	_, err = db.ExecContext(ctx, `
+		ALTER TABLE feature ADD COLUMN IF NOT EXISTS content_schema JSONB;
 	`)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("exec feature content schema: %s: %w", op, err)
 	}
-	defer stmtNewBanner.Close()
 
-	var bannerID int64
-	err = tx.Stmt(stmtNewBanner).QueryRowContext(ctx, content, isActive, featureID).Scan(&bannerID)
+	// responses of requests with Idempotency-Key
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS idempotency_key (
+			key TEXT PRIMARY KEY,
+			fingerprint TEXT NOT NULL,
+			status INTEGER NOT NULL DEFAULT 0,
+			response BYTEA,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idempotency_key_expires_at ON idempotency_key (expires_at);
+		ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+		ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
+	`)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && (pqErr.Code.Name() == "invalid_text_representation" || pqErr.Code.Name() == "foreign_key_violation") {
-			return 0, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
-		}
-		return 0, fmt.Errorf("%s: failed to get last insert banner id: %w", op, err)
+		return nil, fmt.Errorf("exec idempotency key table: %s: %w", op, err)
 	}
 
-	// insert banner with tagIDs
-	for _, tagID := range tagIDs {
-		stmtNewBannerTag, err := s.db.PrepareContext(ctx, `
-			INSERT INTO banner_tag(banner_id, tag_id, feature_id)  VALUES($1, $2, $3)
-		`)
-		if err != nil {
-			return 0, fmt.Errorf("%s: %w", op, err)
-		}
-		defer stmtNewBannerTag.Close()
+	// full-text search over content fields
+	search, err := newSearchConfig(opts.SearchFields, opts.SearchLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := migrateSearch(ctx, db, search); err != nil {
+		return nil, fmt.Errorf("exec search indexes: %s: %w", op, err)
+	}
 
-		_, err = tx.Stmt(stmtNewBannerTag).ExecContext(ctx, bannerID, tagID, featureID)
-		if err != nil {
-			tx.Rollback()
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
-				return 0, fmt.Errorf("%s: %w", op, storage.ErrBannerExists)
-			}
-			return 0, fmt.Errorf("%s: failed to add banner_tag row: %w", op, err)
-		}
+	return &Storage{db: db, replica: replica, search: search}, nil
+}
 
-		// insert data to redis
-		err = redis.SetBanner(ctx, int64(tagID), featureID, content)
-		if err != nil {
-			// tx.Rollback()
-			return 0, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerNotAdd, err)
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgresql.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ContentCheck validates content of banner for feature before it is written, schemas
+// are read by getter in transaction of the write; its error aborts the write and is returned wrapped
+type ContentCheck func(ctx context.Context, schemas schema.SchemaGetter, featureID int64, content []byte) error
+
+// CreateBanner creates banner, it returns its id and time of creation set by database
+func (s *Storage) CreateBanner(ctx context.Context, featureID int64, tagIDs []int, content []byte, isActive bool) (int64, time.Time, error) {
+	const op = "storage.postgresql.CreateBanner"
+
+	var (
+		bannerID  int64
+		createdAt time.Time
+	)
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		bannerID, createdAt, err = createBanner(ctx, tx, featureID, tagIDs, content, isActive, nil)
+		return err
+	})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bannerID, createdAt, nil
+}
+
+func createBanner(ctx context.Context, tx *sql.Tx, featureID int64, tagIDs []int, content []byte, isActive bool, check ContentCheck) (int64, time.Time, error) {
+	// checking required fields
+	if featureID == 0 || content == nil || len(tagIDs) == 0 {
+		return 0, time.Time{}, storage.ErrBannerInvalidData
+	}
+
+	if check != nil {
+		if err := check(ctx, txSchemas{tx}, featureID, content); err != nil {
+			return 0, time.Time{}, err
 		}
 	}
 
-	err = tx.Commit()
+	// insert new banner, created_at and updated_at are the same
+	var (
+		bannerID  int64
+		createdAt time.Time
+	)
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO banner(content, is_active, feature_id) VALUES($1, $2, $3) RETURNING id, updated_at
+	`, content, isActive, featureID).Scan(&bannerID, &createdAt)
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+		if pqErr, ok := err.(*pq.Error); ok && (pqErr.Code.Name() == "invalid_text_representation" || pqErr.Code.Name() == "foreign_key_violation") {
+			return 0, time.Time{}, fmt.Errorf("%w: %w", storage.ErrBannerInvalidData, err)
+		}
+		return 0, time.Time{}, fmt.Errorf("failed to get last insert banner id: %w", err)
 	}
 
-	return bannerID, nil
+	// insert banner with tagIDs
+	if err := replaceBannerTags(ctx, tx, bannerID, featureID, tagIDs); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return bannerID, createdAt, nil
 }
 
-func (s *Storage) UpdateBanner(ctx context.Context, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}) error {
+// UpdateBanner applies update and increments banner version in one transaction,
+// non-nil tagIDs replace tag set of the banner, ifMatch lists acceptable current
+// versions (nil accepts any), it returns new version and feature of banner before update
+func (s *Storage) UpdateBanner(ctx context.Context, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}, ifMatch []int64) (int64, int64, error) {
 	const op = "storage.postgresql.UpdateBanner"
 
-	// Check if feature_id has changed
-	var existingFeatureID int64
-	err := s.db.QueryRowContext(ctx, `SELECT feature_id FROM banner WHERE id = $1`, bannerID).Scan(&existingFeatureID)
+	var version, previousFeatureID int64
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		version, previousFeatureID, err = updateBanner(ctx, tx, bannerID, featureID, tagIDs, content, isActive, ifMatch, nil)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("%s: failed to get existing feature_id: %w", op, err)
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if existingFeatureID != featureID {
-		// Delete existing banner_tag entries for the given banner_id and existingFeatureID
-		stmtDeleteBannerTags, err := s.db.PrepareContext(ctx, `DELETE FROM banner_tag WHERE banner_id = $1 AND feature_id = $2`)
-		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
-		}
+	return version, previousFeatureID, nil
+}
 
-		_, err = stmtDeleteBannerTags.ExecContext(ctx, bannerID, existingFeatureID)
-		if err != nil {
-			return fmt.Errorf("%s: failed to delete existing banner_tag entries: %w", op, err)
-		}
+func updateBanner(ctx context.Context, tx *sql.Tx, bannerID int64, featureID int64, tagIDs []int, content interface{}, isActive interface{}, ifMatch []int64, check ContentCheck) (int64, int64, error) {
+	// lock banner, so version check and update are atomic
+	var (
+		existingFeatureID, version int64
+		existingContent            []byte
+	)
+	err := tx.QueryRowContext(ctx, `SELECT feature_id, version, content FROM banner WHERE id = $1 FOR UPDATE`, bannerID).Scan(&existingFeatureID, &version, &existingContent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, storage.ErrBannerNotExists
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get existing feature_id: %w", err)
+	}
+	if !etag.Matches(version, ifMatch) {
+		return 0, 0, storage.ErrBannerVersion
 	}
 
 	// optional params in query
-	query := `UPDATE banner SET updated_at = CURRENT_TIMESTAMP `
+	query := `UPDATE banner SET updated_at = CURRENT_TIMESTAMP, version = version + 1`
 	var args []interface{}
-	cntArgs := 0
-	queryRow := make([]string, 1, 4)
-	queryRow[0] = ", "
-	var queryParams string
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(", %s = $%d", column, len(args))
+	}
 
+	targetFeatureID := existingFeatureID
+	if featureID != 0 {
+		targetFeatureID = featureID
+	}
+	targetContent := existingContent
 	if content != nil {
-		contentBytes, err := json.Marshal(content)
-		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+		if targetContent, err = json.Marshal(content); err != nil {
+			return 0, 0, err
+		}
+		set("content", targetContent)
+	}
+	if check != nil && (content != nil || targetFeatureID != existingFeatureID) {
+		if err := check(ctx, txSchemas{tx}, targetFeatureID, targetContent); err != nil {
+			return 0, 0, err
 		}
-		cntArgs++
-		queryRow = append(queryRow, fmt.Sprintf("content = ($%v)", cntArgs))
-		args = append(args, contentBytes)
 	}
+
 	switch v := isActive.(type) {
+	case nil:
+		// is_active is not changed
 	case bool:
-		isActiveBool := v
-		cntArgs++
-		queryRow = append(queryRow, fmt.Sprintf("is_active = ($%v)", cntArgs))
-		args = append(args, isActiveBool)
+		set("is_active", v)
 	case string:
-		currentV := v
-		if currentV != "" {
-			return fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+		if v != "" {
+			return 0, 0, storage.ErrBannerInvalidData
 		}
 	default:
-		return fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+		return 0, 0, storage.ErrBannerInvalidData
 	}
 	if featureID != 0 {
-		cntArgs++
-		queryRow = append(queryRow, fmt.Sprintf("feature_id = ($%v)", cntArgs))
-		args = append(args, featureID)
-	}
-	if len(queryRow) > 1 {
-		queryParams = strings.Join(queryRow[1:], ", ")
-		queryParams = ", " + queryParams
-		query += queryParams
+		set("feature_id", featureID)
 	}
 	args = append(args, bannerID)
-	cntArgs++
-	query += fmt.Sprintf(` WHERE id = ($%v);`, cntArgs)
+	query += fmt.Sprintf(` WHERE id = $%d RETURNING version;`, len(args))
 
 	// update banner
-	stmtUpdateBanner, err := s.db.PrepareContext(ctx, query)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&version)
 	if err != nil {
-		fmt.Println(query)
-		return fmt.Errorf(" %s: %w", op, err)
+		if pqErr, ok := err.(*pq.Error); ok && (pqErr.Code.Name() == "invalid_text_representation" || pqErr.Code.Name() == "foreign_key_violation") {
+			return 0, 0, storage.ErrBannerInvalidData
+		}
+		return 0, 0, fmt.Errorf("failed to update banner: %w", err)
 	}
 
-	_, err = stmtUpdateBanner.ExecContext(ctx, args...)
-	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "invalid_text_representation" {
-			return fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
-		}
-		fmt.Println(query)
-		return fmt.Errorf("%s: failed to get last insert banner id: %w", op, err)
+	// tags follow banner, a pair taken by another banner is a conflict
+	if err := replaceBannerTags(ctx, tx, bannerID, targetFeatureID, tagIDs); err != nil {
+		return 0, 0, err
 	}
 
-	// insert banner with tagIDs
+	return version, existingFeatureID, nil
+}
+
+// replaceBannerTags sets tags of banner to tagIDs (nil keeps current ones) under featureID
+func replaceBannerTags(ctx context.Context, tx *sql.Tx, bannerID, featureID int64, tagIDs []int) error {
+	var err error
+	if tagIDs == nil {
+		_, err = tx.ExecContext(ctx, `UPDATE banner_tag SET feature_id = $2 WHERE banner_id = $1`, bannerID, featureID)
+		return mapBannerTagError(err)
+	}
+	if len(tagIDs) == 0 {
+		return storage.ErrBannerInvalidData
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM banner_tag WHERE banner_id = $1`, bannerID); err != nil {
+		return fmt.Errorf("failed to delete banner_tag rows: %w", err)
+	}
 	for _, tagID := range tagIDs {
-		// TODO: change to UPDATE (there's no point now?)
-		stmtNewBannerTag, err := s.db.PrepareContext(ctx, `
-			INSERT INTO banner_tag(banner_id, tag_id, feature_id)  VALUES($1, $2, $3)
-			ON CONFLICT (tag_id, feature_id) DO UPDATE SET banner_id = ($1), tag_id = ($2), feature_id = ($3);
-		`)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO banner_tag(banner_id, tag_id, feature_id) VALUES($1, $2, $3)
+		`, bannerID, tagID, featureID)
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return mapBannerTagError(err)
 		}
+	}
 
-		err = stmtNewBannerTag.QueryRowContext(ctx, bannerID, tagID, featureID).Err()
-		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
-				return fmt.Errorf("%s: %w", op, storage.ErrBannerNotExists)
-			}
-			return fmt.Errorf("%s: failed to update banner_tag row: %w", op, err)
+	return nil
+}
+
+func mapBannerTagError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return storage.ErrBannerExists
+		case "foreign_key_violation":
+			return storage.ErrBannerInvalidData
 		}
 	}
+	if err != nil {
+		return fmt.Errorf("failed to update banner_tag rows: %w", err)
+	}
 
 	return nil
 }
 
-func (s *Storage) DeleteBanner(ctx context.Context, bannerID int64) error {
+// GetBannerByID returns banner with its tags from primary, it is used before writes
+func (s *Storage) GetBannerByID(ctx context.Context, bannerID int64) (*Banner, error) {
+	const op = "storage.postgresql.GetBannerByID"
+
+	var banner Banner
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+		b.id,
+		b.content,
+		COALESCE(b.is_active, false),
+		b.feature_id,
+		b.created_at,
+		b.updated_at,
+		ARRAY(SELECT bt.tag_id FROM banner_tag bt WHERE bt.banner_id = b.id ORDER BY bt.tag_id),
+		b.version
+		FROM banner b
+		WHERE b.id = $1;
+	`, bannerID).Scan(&banner.BannerID, &banner.Content, &banner.IsActive, &banner.FeatureID, &banner.CreatedAT, &banner.UpdatedAT, pq.Array(&banner.TagIDs), &banner.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get banner: %w", op, err)
+	}
+
+	return &banner, nil
+}
+
+// DeleteBanner removes banner if its version is one of ifMatch (nil accepts any),
+// it returns feature of removed banner
+func (s *Storage) DeleteBanner(ctx context.Context, bannerID int64, ifMatch []int64) (int64, error) {
 	const op = "storage.postgresql.DeleteBanner"
 
+	var featureID int64
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		featureID, err = deleteBanner(ctx, tx, bannerID, ifMatch)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return featureID, nil
+}
+
+func deleteBanner(ctx context.Context, tx *sql.Tx, bannerID int64, ifMatch []int64) (int64, error) {
 	// checking required field
 	if bannerID == 0 {
-		return fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+		return 0, storage.ErrBannerInvalidData
 	}
 
-	tx, err := s.db.Begin()
+	var version, featureID int64
+	err := tx.QueryRowContext(ctx, `SELECT version, feature_id FROM banner WHERE id = $1 FOR UPDATE`, bannerID).Scan(&version, &featureID)
+	// check if exist banner
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrBannerNotExists
+	}
 	if err != nil {
-		return fmt.Errorf("%s: failed to start transaction: %w", op, err)
+		return 0, fmt.Errorf("failed to get banner version: %w", err)
+	}
+	if !etag.Matches(version, ifMatch) {
+		return 0, storage.ErrBannerVersion
 	}
-	defer tx.Rollback()
 
 	// delete banner
-	stmtDeleteBanner, err := s.db.PrepareContext(ctx, `
-		DELETE FROM banner
-		WHERE id = ($1);
-	`)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM banner WHERE id = $1`, bannerID); err != nil {
+		return 0, fmt.Errorf("failed delete row: %w", err)
 	}
-	defer stmtDeleteBanner.Close()
 
-	result, err := tx.Stmt(stmtDeleteBanner).ExecContext(ctx, bannerID)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("%s: failed delete row: %w", op, err)
-	}
+	return featureID, nil
+}
 
-	// cnt affected rows
-	rowsAffected, err := result.RowsAffected()
+// inTx runs fn in transaction, committing it if fn succeeds
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: error getting rows affected: %w", op, err)
+		return fmt.Errorf("failed to start transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// check if exist banner
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: %w", op, storage.ErrBannerNotExists)
+	if err := fn(tx); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Storage) GetBanner(ctx context.Context, tagID, featureID int64) (json.RawMessage, error) {
+// resolveBannersQuery finds banner for every feature of $1 for tag $2, falling
+// back up the tag hierarchy to the nearest ancestor which has a banner for the
+// feature and then to the default banner of the feature
+const resolveBannersQuery = `
+	WITH RECURSIVE ancestors(id, parent_id, depth) AS (
+		SELECT id, parent_id, 0 FROM tag WHERE id = $2
+		UNION ALL
+		SELECT t.id, t.parent_id, a.depth + 1
+		FROM tag t
+		JOIN ancestors a ON t.id = a.parent_id
+		WHERE a.depth < $3
+	)
+	SELECT DISTINCT ON (feature_id) feature_id, tag_id, content, fallback, version, updated_at
+	FROM (
+		SELECT b.feature_id, bt.tag_id, b.content, false AS fallback, a.depth, b.version, b.updated_at
+		FROM ancestors a
+		JOIN banner_tag bt ON bt.tag_id = a.id
+		JOIN banner b ON b.id = bt.banner_id
+		WHERE b.feature_id = ANY($1)
+		UNION ALL
+		SELECT f.id, 0, b.content, true, 0, b.version, b.updated_at
+		FROM feature f
+		JOIN banner b ON b.id = f.default_banner_id AND b.feature_id = f.id
+		WHERE f.id = ANY($1)
+	) candidates
+	ORDER BY feature_id, fallback, depth;
+`
+
+func (s *Storage) GetBanner(ctx context.Context, tagID, featureID int64) (*storage.ResolvedBanner, error) {
 	const op = "storage.postgresql.GetBanner"
 
 	// checking required fields
@@ -390,119 +586,92 @@ func (s *Storage) GetBanner(ctx context.Context, tagID, featureID int64) (json.R
 	}
 
 	// get banner
-	stmtGetBanner, err := s.db.PrepareContext(ctx, `
-        SELECT b.content
-        FROM banner b
-        JOIN banner_tag bt ON b.id = bt.banner_id
-        WHERE b.feature_id = $1 AND bt.tag_id = $2;
-	`)
+	banners, err := s.GetTagBanners(ctx, tagID, []int64{featureID})
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: failed get content row: %w", op, err)
 	}
 
-	var content json.RawMessage
-	err = stmtGetBanner.QueryRowContext(ctx, featureID, tagID).Scan(&content)
-	if err != nil {
-		// if not exist
-		if strings.Contains(err.Error(), "no rows in result set") {
-			return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
-		}
-		return nil, fmt.Errorf("%s: failed get content row: %w", op, err)
+	// if not exist
+	banner, ok := banners[featureID]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
 	}
 
-	return content, nil
+	return banner, nil
 }
 
-func (s *Storage) GetBannerList(ctx context.Context, featureID, tagID, limit, offset int64) ([]*Banner, error) {
-	const op = "storage.postgresql.GetBannerList"
+// GetTagBanners resolves banners of several features for tag in a single query,
+// features without banner are absent in result
+func (s *Storage) GetTagBanners(ctx context.Context, tagID int64, featureIDs []int64) (map[int64]*storage.ResolvedBanner, error) {
+	const op = "storage.postgresql.GetTagBanners"
 
-	// optional params in query
-	query := `
-		SELECT
-		b.*,
-		bt.tag_id
-		FROM
-		banner b
-		LEFT JOIN banner_tag bt ON b.id = bt.banner_id	
-	`
-	var args []interface{}
-	cntArgs := 0
-
-	if featureID != 0 && tagID != 0 {
-		cntArgs++
-		query += fmt.Sprintf("WHERE b.feature_id = ($%v) AND ", cntArgs)
-		args = append(args, featureID)
-		cntArgs++
-		query += fmt.Sprintf("bt.tag_id = ($%v)", cntArgs)
-		args = append(args, tagID)
-	} else if featureID != 0 {
-		cntArgs++
-		query += fmt.Sprintf("WHERE b.feature_id = ($%v)", cntArgs)
-		args = append(args, featureID)
-	} else if tagID != 0 {
-		cntArgs++
-		query += fmt.Sprintf("WHERE bt.tag_id = ($%v)", cntArgs)
-		args = append(args, tagID)
-	}
-	query += "ORDER BY id "
-	if limit != 0 {
-		cntArgs++
-		query += fmt.Sprintf("LIMIT ($%v)", cntArgs)
-		args = append(args, limit)
-	}
-	cntArgs++
-	query += fmt.Sprintf("OFFSET ($%v);", cntArgs)
-	args = append(args, offset)
-
-	// get banner list
-	stmtGetBannerList, err := s.db.PrepareContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+	var banners map[int64]*storage.ResolvedBanner
+	err := s.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, resolveBannersQuery, pq.Array(featureIDs), tagID, maxTagDepth)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		banners = make(map[int64]*storage.ResolvedBanner, len(featureIDs))
+		for rows.Next() {
+			var (
+				featureID int64
+				banner    storage.ResolvedBanner
+			)
+			if err := rows.Scan(&featureID, &banner.TagID, &banner.Content, &banner.Fallback, &banner.Version, &banner.UpdatedAt); err != nil {
+				return err
+			}
+			banners[featureID] = &banner
+		}
 
-	rows, err := stmtGetBannerList.QueryContext(ctx, args...)
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to execute query: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to get banners: %w", op, err)
 	}
-	defer rows.Close()
 
-	bannerMap := make(map[int64]*Banner)
+	return banners, nil
+}
 
-	for rows.Next() {
-		var banner Banner
-		var currentTagID int64
-		// var contentJSON []byte
-		// read the lines from the query result and add them to the list
-		var stringContent string
-		if err := rows.Scan(&banner.BannerID, &stringContent, &banner.IsActive, &banner.FeatureID, &banner.CreatedAT, &banner.UpdatedAT, &currentTagID); err != nil {
-			return nil, fmt.Errorf("%s: failed to scan rows: %w", op, err)
+// GetActiveBanners returns content of active banners for every tag:feature pair,
+// ordered by banner_tag id, starting after afterID
+func (s *Storage) GetActiveBanners(ctx context.Context, afterID, limit int64) ([]storage.CacheEntry, int64, error) {
+	const op = "storage.postgresql.GetActiveBanners"
+
+	var (
+		entries []storage.CacheEntry
+		lastID  int64
+	)
+	err := s.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, `
+			SELECT bt.id, bt.tag_id, b.feature_id, b.content, b.version, b.updated_at
+			FROM banner b
+			JOIN banner_tag bt ON b.id = bt.banner_id
+			WHERE b.is_active AND bt.id > $1
+			ORDER BY bt.id
+			LIMIT $2;
+		`, afterID, limit)
+		if err != nil {
+			return err
 		}
-		banner.Content = stringContent
-
-		// check if a banner with this ID already exists
-		if existingBanner, found := bannerMap[banner.BannerID]; found {
-			// if found, add a new tag_id
-			existingBanner.TagIDs = append(existingBanner.TagIDs, currentTagID)
-		} else {
-			// if you haven't found it, create a new banner
-			banner.TagIDs = append(banner.TagIDs, currentTagID)
-			bannerMap[banner.BannerID] = &banner
+		defer rows.Close()
+
+		entries = make([]storage.CacheEntry, 0, limit)
+		lastID = afterID
+		for rows.Next() {
+			var entry storage.CacheEntry
+			if err := rows.Scan(&lastID, &entry.TagID, &entry.FeatureID, &entry.Content, &entry.Version, &entry.UpdatedAt); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
 		}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: error during iteration: %w", op, err)
-	}
 
-	resultList := getBannerListFromMap(bannerMap)
-	return resultList, nil
-}
-
-func getBannerListFromMap(bannerMap map[int64]*Banner) []*Banner {
-	bannerList := make([]*Banner, 0, len(bannerMap))
-
-	for _, banner := range bannerMap {
-		bannerList = append(bannerList, banner)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: failed to get active banners: %w", op, err)
 	}
 
-	return bannerList
+	return entries, lastID, nil
 }
@@ -0,0 +1,355 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/lib/pq"
+)
+
+const (
+	FilterContains   = "contains"
+	FilterStartsWith = "starts_with"
+	FilterEqual      = "="
+	FilterNotEqual   = "!="
+	FilterExists     = "exists"
+)
+
+// highlightStart and highlightStop mark found words in ts_headline output, they are
+// private use characters, so fragments are HTML escaped before they become <mark>
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var (
+	highlight         = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2", highlightStart, highlightStop)
+	highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+)
+
+var searchFieldRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// JSONFilter is a condition on banner content, e.g. content.url contains "promo"
+type JSONFilter struct {
+	Path  []string
+	Op    string
+	Value interface{}
+}
+
+type SearchQuery struct {
+	// Query is a full-text query in websearch syntax
+	Query   string
+	Filters []JSONFilter
+	Limit   int64
+	Offset  int64
+}
+
+type SearchResult struct {
+	Banner
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// searchConfig is full-text document of banner content built from configured fields
+type searchConfig struct {
+	fields   []string
+	language string
+}
+
+func newSearchConfig(fields []string, language string) (searchConfig, error) {
+	if len(fields) == 0 {
+		fields = []string{"title", "text"}
+	}
+	if language == "" {
+		language = "simple"
+	}
+
+	for _, field := range fields {
+		if !searchFieldRe.MatchString(field) {
+			return searchConfig{}, fmt.Errorf("invalid search field %q", field)
+		}
+	}
+
+	return searchConfig{fields: fields, language: language}, nil
+}
+
+func (c searchConfig) regconfig() string {
+	return pq.QuoteLiteral(c.language) + "::regconfig"
+}
+
+func (c searchConfig) field(alias, field string) string {
+	return fmt.Sprintf("COALESCE(%scontent->>%s, '')", alias, pq.QuoteLiteral(field))
+}
+
+// document returns tsvector expression, it must match the index expression
+func (c searchConfig) document(alias string) string {
+	parts := make([]string, len(c.fields))
+	for i, field := range c.fields {
+		parts[i] = c.field(alias, field)
+	}
+
+	return fmt.Sprintf("to_tsvector(%s, %s)", c.regconfig(), strings.Join(parts, " || ' ' || "))
+}
+
+// indexName depends on document, so changed fields get a new index
+func (c searchConfig) indexName() string {
+	h := fnv.New32a()
+	h.Write([]byte(c.document("")))
+
+	return fmt.Sprintf("banner_content_fts_%x", h.Sum32())
+}
+
+// migrateSearch creates indexes for full-text search and content filters. They are
+// built concurrently, so banner writes are not blocked, and CONCURRENTLY can't run
+// in transaction, so every statement is executed on its own. Indexes of documents
+// of previously configured fields are dropped
+func migrateSearch(ctx context.Context, db *sql.DB, search searchConfig) error {
+	name := search.indexName()
+	if err := createIndexConcurrently(ctx, db, name, fmt.Sprintf(`ON banner USING GIN ((%s))`, search.document(""))); err != nil {
+		return err
+	}
+	if err := createIndexConcurrently(ctx, db, "banner_content_path", `ON banner USING GIN (content jsonb_path_ops)`); err != nil {
+		return err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT indexname FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = 'banner'
+		AND indexname LIKE 'banner\_content\_fts\_%' AND indexname <> $1
+	`, name)
+	if err != nil {
+		return fmt.Errorf("failed to get search indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var stale []string
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			return err
+		}
+		stale = append(stale, index)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, index := range stale {
+		if _, err := db.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+pq.QuoteIdentifier(index)); err != nil {
+			return fmt.Errorf("failed to drop search index %s: %w", index, err)
+		}
+	}
+
+	return nil
+}
+
+// createIndexConcurrently creates index by definition unless it exists,
+// index left invalid by interrupted build is dropped and built again
+func createIndexConcurrently(ctx context.Context, db *sql.DB, name, definition string) error {
+	var valid bool
+	err := db.QueryRowContext(ctx, `
+		SELECT i.indisvalid FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE c.relname = $1 AND c.relnamespace = current_schema()::regnamespace
+	`, name).Scan(&valid)
+	switch {
+	case err == nil && valid:
+		return nil
+	case err == nil:
+		if _, err := db.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+pq.QuoteIdentifier(name)); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to get index %s: %w", name, err)
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE INDEX CONCURRENTLY IF NOT EXISTS %s %s`, pq.QuoteIdentifier(name), definition)); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ParseJSONFilter parses "content.<path> <op> [value]" where op is one of
+// contains, starts_with, =, != or exists and value is JSON or a bare word
+func ParseJSONFilter(expr string) (JSONFilter, error) {
+	const op = "storage.postgresql.ParseJSONFilter"
+
+	var filter JSONFilter
+
+	parts := strings.SplitN(strings.TrimSpace(expr), " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "content.") {
+		return filter, fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+	}
+
+	filter.Path = strings.Split(strings.TrimPrefix(parts[0], "content."), ".")
+	for _, key := range filter.Path {
+		if key == "" {
+			return filter, fmt.Errorf("%s: %w", op, storage.ErrBannerInvalidData)
+		}
+	}
+
+	filter.Op = parts[1]
+	switch filter.Op {
+	case FilterExists:
+		return filter, nil
+	case FilterContains, FilterStartsWith, FilterEqual, FilterNotEqual:
+	default:
+		return filter, fmt.Errorf("%s: unknown operator %q: %w", op, filter.Op, storage.ErrBannerInvalidData)
+	}
+
+	if len(parts) < 3 {
+		return filter, fmt.Errorf("%s: no value: %w", op, storage.ErrBannerInvalidData)
+	}
+	if err := json.Unmarshal([]byte(parts[2]), &filter.Value); err != nil {
+		filter.Value = parts[2]
+	}
+
+	return filter, nil
+}
+
+// condition returns SQL condition of filter, arguments are numbered from argN
+func (f JSONFilter) condition(argN int) (string, []interface{}, error) {
+	path := pq.Array(f.Path)
+
+	switch f.Op {
+	case FilterExists:
+		return fmt.Sprintf("b.content #> $%d IS NOT NULL", argN), []interface{}{path}, nil
+	case FilterEqual, FilterNotEqual:
+		// containment uses jsonb_path_ops index
+		var doc interface{} = f.Value
+		for i := len(f.Path) - 1; i >= 0; i-- {
+			doc = map[string]interface{}{f.Path[i]: doc}
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return "", nil, err
+		}
+		cond := fmt.Sprintf("b.content @> $%d::jsonb", argN)
+		if f.Op == FilterNotEqual {
+			cond = "NOT " + cond
+		}
+		return cond, []interface{}{string(data)}, nil
+	case FilterContains, FilterStartsWith:
+		value := escapeLike(fmt.Sprint(f.Value)) + "%"
+		if f.Op == FilterContains {
+			value = "%" + value
+		}
+		return fmt.Sprintf("b.content #>> $%d ILIKE $%d", argN, argN+1), []interface{}{path, value}, nil
+	default:
+		return "", nil, storage.ErrBannerInvalidData
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchBanners finds banners by full-text query over configured content
+// fields and content filters, ordered by rank, with matches highlighted
+func (s *Storage) SearchBanners(ctx context.Context, query SearchQuery) ([]*SearchResult, error) {
+	const op = "storage.postgresql.SearchBanners"
+
+	if query.Query == "" && len(query.Filters) == 0 {
+		return nil, fmt.Errorf("%s: empty search: %w", op, storage.ErrBannerInvalidData)
+	}
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	rank := "0::real"
+	highlights := "NULL::jsonb"
+	if query.Query != "" {
+		args = append(args, query.Query)
+		tsquery := fmt.Sprintf("websearch_to_tsquery(%s, $1)", s.search.regconfig())
+		conds = append(conds, fmt.Sprintf("%s @@ %s", s.search.document("b."), tsquery))
+		rank = fmt.Sprintf("ts_rank(%s, %s)", s.search.document("b."), tsquery)
+
+		pairs := make([]string, 0, 2*len(s.search.fields))
+		for _, field := range s.search.fields {
+			pairs = append(pairs, pq.QuoteLiteral(field), fmt.Sprintf("ts_headline(%s, %s, %s, %s)",
+				s.search.regconfig(), s.search.field("b.", field), tsquery, pq.QuoteLiteral(highlight)))
+		}
+		highlights = fmt.Sprintf("jsonb_build_object(%s)", strings.Join(pairs, ", "))
+	}
+
+	for _, filter := range query.Filters {
+		cond, condArgs, err := filter.condition(len(args) + 1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	sqlQuery := fmt.Sprintf(`
+		SELECT
+		b.id,
+		b.content,
+		COALESCE(b.is_active, false),
+		b.feature_id,
+		b.created_at,
+		b.updated_at,
+		ARRAY(SELECT bt.tag_id FROM banner_tag bt WHERE bt.banner_id = b.id ORDER BY bt.tag_id),
+		b.version,
+		%s AS rank,
+		%s
+		FROM banner b
+		WHERE %s
+		ORDER BY rank DESC, b.id
+		LIMIT NULLIF($%d, 0) OFFSET $%d;
+	`, rank, highlights, strings.Join(conds, " AND "), len(args)-1, len(args))
+
+	var results []*SearchResult
+	err := s.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, sqlQuery, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		results = make([]*SearchResult, 0)
+		for rows.Next() {
+			var (
+				result     SearchResult
+				highlights []byte
+			)
+			if err := rows.Scan(&result.BannerID, &result.Content, &result.IsActive, &result.FeatureID, &result.CreatedAT, &result.UpdatedAT, pq.Array(&result.TagIDs), &result.Version, &result.Rank, &highlights); err != nil {
+				return err
+			}
+			if highlights != nil {
+				if err := json.Unmarshal(highlights, &result.Highlights); err != nil {
+					return err
+				}
+				for field, fragment := range result.Highlights {
+					result.Highlights[field] = highlightHTML(fragment)
+				}
+			}
+			results = append(results, &result)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to search banners: %w", op, err)
+	}
+
+	return results, nil
+}
+
+// highlightHTML escapes fragment of content, so markup in content is shown as text
+// and only found words are marked
+func highlightHTML(fragment string) string {
+	return highlightReplacer.Replace(html.EscapeString(fragment))
+}
@@ -0,0 +1,124 @@
+package postgresql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/lib/pq"
+)
+
+func TestParseJSONFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want JSONFilter
+	}{
+		{expr: "content.url contains promo", want: JSONFilter{Path: []string{"url"}, Op: FilterContains, Value: "promo"}},
+		{expr: " content.title starts_with Big sale ", want: JSONFilter{Path: []string{"title"}, Op: FilterStartsWith, Value: "Big sale"}},
+		{expr: `content.meta.price = 100`, want: JSONFilter{Path: []string{"meta", "price"}, Op: FilterEqual, Value: float64(100)}},
+		{expr: `content.title = "100"`, want: JSONFilter{Path: []string{"title"}, Op: FilterEqual, Value: "100"}},
+		{expr: `content.flags != {"new": true}`, want: JSONFilter{Path: []string{"flags"}, Op: FilterNotEqual, Value: map[string]interface{}{"new": true}}},
+		{expr: "content.items exists", want: JSONFilter{Path: []string{"items"}, Op: FilterExists}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseJSONFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseJSONFilter: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJSONFilter = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONFilterInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"content.url",
+		"url contains promo",
+		"content. exists",
+		"content.meta..price = 1",
+		"content.url like promo",
+		"content.url contains",
+		"content.url =",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseJSONFilter(expr); !errors.Is(err, storage.ErrBannerInvalidData) {
+				t.Errorf("ParseJSONFilter error = %v, want ErrBannerInvalidData", err)
+			}
+		})
+	}
+}
+
+func TestJSONFilterCondition(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter JSONFilter
+		cond   string
+		args   []interface{}
+	}{
+		{
+			name:   "exists",
+			filter: JSONFilter{Path: []string{"meta", "price"}, Op: FilterExists},
+			cond:   "b.content #> $3 IS NOT NULL",
+			args:   []interface{}{pq.Array([]string{"meta", "price"})},
+		},
+		{
+			name:   "equal",
+			filter: JSONFilter{Path: []string{"meta", "price"}, Op: FilterEqual, Value: float64(100)},
+			cond:   "b.content @> $3::jsonb",
+			args:   []interface{}{`{"meta":{"price":100}}`},
+		},
+		{
+			name:   "not equal",
+			filter: JSONFilter{Path: []string{"title"}, Op: FilterNotEqual, Value: "sale"},
+			cond:   "NOT b.content @> $3::jsonb",
+			args:   []interface{}{`{"title":"sale"}`},
+		},
+		{
+			name:   "contains with wildcards",
+			filter: JSONFilter{Path: []string{"url"}, Op: FilterContains, Value: `50%_off\`},
+			cond:   "b.content #>> $3 ILIKE $4",
+			args:   []interface{}{pq.Array([]string{"url"}), `%50\%\_off\\%`},
+		},
+		{
+			name:   "starts with",
+			filter: JSONFilter{Path: []string{"title"}, Op: FilterStartsWith, Value: "Big"},
+			cond:   "b.content #>> $3 ILIKE $4",
+			args:   []interface{}{pq.Array([]string{"title"}), "Big%"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, args, err := tt.filter.condition(3)
+			if err != nil {
+				t.Fatalf("condition: %v", err)
+			}
+			if cond != tt.cond {
+				t.Errorf("condition = %q, want %q", cond, tt.cond)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{fragment: "big \uE000sale\uE001 today", want: "big <mark>sale</mark> today"},
+		{fragment: "<img src=x onerror=\"alert(1)\"> \uE000sale\uE001", want: `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>sale</mark>`},
+		{fragment: "fish & \uE000chips\uE001 </mark>", want: "fish &amp; <mark>chips</mark> &lt;/mark&gt;"},
+	}
+	for _, tt := range tests {
+		if got := highlightHTML(tt.fragment); got != tt.want {
+			t.Errorf("highlightHTML(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+)
+
+type Tag struct {
+	TagID    int64  `json:"tag_id"`
+	ParentID *int64 `json:"parent_id"`
+	Meta
+}
+
+// maxTagDepth limits walking up the tag hierarchy
+const maxTagDepth = 32
+
+const tagActiveBannersQuery = `
+	SELECT EXISTS (
+		SELECT 1
+		FROM banner b
+		JOIN banner_tag bt ON b.id = bt.banner_id
+		WHERE bt.tag_id = $1 AND b.is_active
+	)
+`
+
+// CreateTag creates tag, zero parentID means top level tag
+func (s *Storage) CreateTag(ctx context.Context, name, description, owner string, parentID int64) (int64, error) {
+	const op = "storage.postgresql.CreateTag"
+
+	id, err := s.createMeta(ctx, "tag", tagErrors, name, description, owner, column{"parent_id", nullID(parentID)})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetTag(ctx context.Context, tagID int64) (*Tag, error) {
+	const op = "storage.postgresql.GetTag"
+
+	var tag Tag
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, parent_id, `+metaColumns+`
+		FROM tag
+		WHERE id = $1;
+	`, tagID).Scan(&tag.TagID, &tag.ParentID, &tag.Name, &tag.Description, &tag.Owner, &tag.Archived, &tag.CreatedAT, &tag.UpdatedAT)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrTagNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get tag: %w", op, err)
+	}
+
+	return &tag, nil
+}
+
+// GetTagIDByName returns id of tag with unique name
+func (s *Storage) GetTagIDByName(ctx context.Context, name string) (int64, error) {
+	const op = "storage.postgresql.GetTagIDByName"
+
+	id, err := s.findMeta(ctx, "tag", tagErrors, name)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetTagList returns tags ordered by id, archived ones only if includeArchived
+func (s *Storage) GetTagList(ctx context.Context, includeArchived bool, limit, offset int64) ([]*Tag, error) {
+	const op = "storage.postgresql.GetTagList"
+
+	query := `SELECT id, parent_id, ` + metaColumns + ` FROM tag `
+	if !includeArchived {
+		query += `WHERE NOT archived `
+	}
+	query += `ORDER BY id LIMIT NULLIF($1, 0) OFFSET $2;`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute query: %w", op, err)
+	}
+	defer rows.Close()
+
+	tags := make([]*Tag, 0)
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.TagID, &tag.ParentID, &tag.Name, &tag.Description, &tag.Owner, &tag.Archived, &tag.CreatedAT, &tag.UpdatedAT); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan rows: %w", op, err)
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: error during iteration: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag refuses to archive tag with active banners unless force is set,
+// nil parentID keeps the parent, zero makes the tag top level
+func (s *Storage) UpdateTag(ctx context.Context, tagID int64, upd MetaUpdate, parentID *int64, force bool) error {
+	const op = "storage.postgresql.UpdateTag"
+
+	var (
+		check func(tx *sql.Tx) error
+		extra []column
+	)
+	if parentID != nil {
+		check = func(tx *sql.Tx) error {
+			return checkTagParent(ctx, tx, tagID, *parentID)
+		}
+		extra = append(extra, column{"parent_id", nullID(*parentID)})
+	}
+
+	if err := s.updateMeta(ctx, "tag", tagErrors, tagID, upd, tagActiveBannersQuery, force, check, extra...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteTag(ctx context.Context, tagID int64) error {
+	const op = "storage.postgresql.DeleteTag"
+
+	if err := s.deleteMeta(ctx, "tag", tagErrors, tagID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// checkTagParent makes sure parentID is not the tag itself or its descendant
+func checkTagParent(ctx context.Context, tx *sql.Tx, tagID, parentID int64) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == tagID {
+		return storage.ErrTagInvalidParent
+	}
+
+	var cycle bool
+	err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors(id, parent_id) AS (
+			SELECT id, parent_id FROM tag WHERE id = $1
+			UNION
+			SELECT t.id, t.parent_id
+			FROM tag t
+			JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2);
+	`, parentID, tagID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return storage.ErrTagInvalidParent
+	}
+
+	return nil
+}
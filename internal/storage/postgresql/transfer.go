@@ -0,0 +1,303 @@
+package postgresql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JustForWorld/banner-shift/internal/storage"
+	"github.com/lib/pq"
+)
+
+const exportBatchSize = 500
+
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+type ImportOptions struct {
+	// Policy is applied when a record conflicts with existing banner: skip, overwrite or fail
+	Policy string
+	// MatchByID finds existing banner by banner_id of record instead of its tag and
+	// feature pairs, ids are only stable when importing back into the same database
+	MatchByID bool
+	// DryRun rolls back all changes, the report is the same as of a real import
+	DryRun bool
+	// Check validates content of records before transaction of import is opened
+	Check ContentCheck
+}
+
+// ImportItem is outcome of a record, Line points into the imported file
+type ImportItem struct {
+	Line     int
+	BannerID int64
+	Action   string
+	Err      error
+}
+
+type ImportReport struct {
+	Created int
+	Updated int
+	Skipped int
+	Failed  int
+	// Aborted is set when fail policy stopped import and nothing was changed
+	Aborted bool
+	Items   []ImportItem
+}
+
+// ExportBanners calls fn for every banner ordered by id, banners are read in
+// batches, so export does not hold a long transaction
+func (s *Storage) ExportBanners(ctx context.Context, fn func(*Banner) error) error {
+	const op = "storage.postgresql.ExportBanners"
+
+	var afterID int64
+	for {
+		var banners []*Banner
+		err := s.read(ctx, func(db *sql.DB) error {
+			rows, err := db.QueryContext(ctx, `
+				SELECT
+				b.id,
+				b.content,
+				COALESCE(b.is_active, false),
+				b.feature_id,
+				b.created_at,
+				b.updated_at,
+				ARRAY(SELECT bt.tag_id FROM banner_tag bt WHERE bt.banner_id = b.id ORDER BY bt.tag_id),
+				b.version
+				FROM banner b
+				WHERE b.id > $1
+				ORDER BY b.id
+				LIMIT $2;
+			`, afterID, exportBatchSize)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			banners = make([]*Banner, 0, exportBatchSize)
+			for rows.Next() {
+				var banner Banner
+				if err := rows.Scan(&banner.BannerID, &banner.Content, &banner.IsActive, &banner.FeatureID, &banner.CreatedAT, &banner.UpdatedAT, pq.Array(&banner.TagIDs), &banner.Version); err != nil {
+					return err
+				}
+				banners = append(banners, &banner)
+			}
+
+			return rows.Err()
+		})
+		if err != nil {
+			return fmt.Errorf("%s: failed to get banners: %w", op, err)
+		}
+
+		for _, banner := range banners {
+			if err := fn(banner); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if len(banners) < exportBatchSize {
+			return nil
+		}
+		afterID = banners[len(banners)-1].BannerID
+	}
+}
+
+// ImportBanners creates banners returned by next until io.EOF in one transaction,
+// every record is applied in a savepoint, so its failure doesn't affect others.
+// Records are read and checked before transaction is opened, so slow upload doesn't hold it.
+// Banner ids of records are only used to find existing banners with MatchByID, new banners get new ids
+func (s *Storage) ImportBanners(ctx context.Context, next func() (line int, banner *Banner, err error), opts ImportOptions) (*ImportReport, error) {
+	const op = "storage.postgresql.ImportBanners"
+
+	switch opts.Policy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, fmt.Errorf("%s: unknown conflict policy %q: %w", op, opts.Policy, storage.ErrBannerInvalidData)
+	}
+
+	var (
+		banners []*Banner
+		items   []ImportItem
+	)
+	for {
+		line, banner, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+		}
+
+		item := ImportItem{Line: line, BannerID: banner.BannerID}
+		if err := s.checkImport(ctx, banner, opts.Check); err != nil {
+			item.Action, item.Err = ImportFailed, err
+		}
+		banners = append(banners, banner)
+		items = append(items, item)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to start transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	report := &ImportReport{Items: make([]ImportItem, 0, len(items))}
+	for i, banner := range banners {
+		item := items[i]
+		if item.Action != ImportFailed {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT import_record`); err != nil {
+				return nil, fmt.Errorf("%s: failed to create savepoint: %w", op, err)
+			}
+
+			item = importBanner(ctx, tx, banner, opts)
+			item.Line = items[i].Line
+
+			// skipped record may have failed statement, which aborts transaction until rollback
+			if item.Action == ImportFailed || item.Action == ImportSkipped {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_record`); err != nil {
+					return nil, fmt.Errorf("%s: failed to roll back to savepoint: %w", op, err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT import_record`); err != nil {
+				return nil, fmt.Errorf("%s: failed to release savepoint: %w", op, err)
+			}
+		}
+		report.Items = append(report.Items, item)
+
+		switch item.Action {
+		case ImportCreated:
+			report.Created++
+		case ImportUpdated:
+			report.Updated++
+		case ImportSkipped:
+			report.Skipped++
+		case ImportFailed:
+			report.Failed++
+		}
+		if item.Action == ImportFailed && opts.Policy == ConflictFail {
+			report.Aborted = true
+			return report, nil
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+	}
+
+	return report, nil
+}
+
+// checkImport validates record before import, feature_id is required,
+// so content is checked against schema of the feature it is written with
+func (s *Storage) checkImport(ctx context.Context, banner *Banner, check ContentCheck) error {
+	if banner.FeatureID == 0 || banner.Content == nil || bytes.Equal(banner.Content, []byte("null")) {
+		return storage.ErrBannerInvalidData
+	}
+	if check == nil {
+		return nil
+	}
+
+	return check(ctx, s, banner.FeatureID, banner.Content)
+}
+
+// importBanner writes record checked by checkImport
+func importBanner(ctx context.Context, tx *sql.Tx, banner *Banner, opts ImportOptions) ImportItem {
+	item := ImportItem{BannerID: banner.BannerID}
+
+	tagIDs := make([]int, len(banner.TagIDs))
+	for i, tagID := range banner.TagIDs {
+		tagIDs[i] = int(tagID)
+	}
+
+	existingID, err := conflictingBanner(ctx, tx, banner, opts.MatchByID)
+	switch {
+	case err != nil:
+	case existingID != 0 && opts.Policy == ConflictSkip:
+		item.Action, item.BannerID = ImportSkipped, existingID
+		return item
+	case existingID != 0 && opts.Policy == ConflictFail:
+		item.Action, item.BannerID, item.Err = ImportFailed, existingID, storage.ErrBannerExists
+		return item
+	case existingID != 0:
+		item.BannerID = existingID
+		_, _, err = updateBanner(ctx, tx, existingID, banner.FeatureID, tagIDs, banner.Content, banner.IsActive, nil, nil)
+		item.Action = ImportUpdated
+	default:
+		item.BannerID, _, err = createBanner(ctx, tx, banner.FeatureID, tagIDs, banner.Content, banner.IsActive, nil)
+		item.Action = ImportCreated
+	}
+
+	// tag and feature pairs of several banners or, matching by id, of another banner
+	if errors.Is(err, storage.ErrBannerExists) && opts.Policy == ConflictSkip {
+		item.Action, item.BannerID = ImportSkipped, banner.BannerID
+		return item
+	}
+	if err != nil {
+		item.Action, item.BannerID, item.Err = ImportFailed, banner.BannerID, err
+	}
+
+	return item
+}
+
+// conflictingBanner returns id of existing banner record conflicts with or 0, by default
+// it is the banner holding any of tag and feature pairs of record, ErrBannerExists is
+// returned if the pairs belong to several banners
+func conflictingBanner(ctx context.Context, tx *sql.Tx, banner *Banner, matchByID bool) (int64, error) {
+	if matchByID {
+		if banner.BannerID == 0 {
+			return 0, nil
+		}
+
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM banner WHERE id = $1)`, banner.BannerID).Scan(&exists)
+		if err != nil || !exists {
+			return 0, err
+		}
+
+		return banner.BannerID, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT banner_id FROM banner_tag WHERE feature_id = $1 AND tag_id = ANY($2)
+	`, banner.FeatureID, pq.Array(banner.TagIDs))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var bannerIDs []int64
+	for rows.Next() {
+		var bannerID int64
+		if err := rows.Scan(&bannerID); err != nil {
+			return 0, err
+		}
+		bannerIDs = append(bannerIDs, bannerID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	switch len(bannerIDs) {
+	case 0:
+		return 0, nil
+	case 1:
+		return bannerIDs[0], nil
+	default:
+		return 0, storage.ErrBannerExists
+	}
+}
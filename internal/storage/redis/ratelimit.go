@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokenScript refills bucket by time passed since last take and takes one
+// token from it; time of Redis is used, so replicas with skewed clocks share
+// buckets correctly. Bucket expires once it would be full again
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(bucket[1]) or burst
+local at = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - at) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// TakeToken takes token from bucket of key shared by all replicas, bucket
+// holds up to burst tokens and is refilled with rate tokens per second
+func (s *Storage) TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	const op = "storage.redis.TakeToken"
+
+	var res []interface{}
+	err := s.call(func() (err error) {
+		res, err = takeTokenScript.Run(ctx, s.db, []string{s.keyPrefix + "ratelimit:" + key}, rate, burst).Slice()
+		return err
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("%s: unexpected script result %v", op, res)
+	}
+
+	allowed, _ := res[0].(int64)
+	remaining, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return allowed == 1, remaining, nil
+}
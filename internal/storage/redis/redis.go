@@ -2,54 +2,443 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/JustForWorld/banner-shift/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
+// notFoundValue marks a cached "banner not found" result (negative caching)
+const notFoundValue = "\x00not_found"
+
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
 type Storage struct {
-	db *redis.Client
+	db         redis.UniversalClient
+	breaker    *breaker
+	keyPrefix  string
+	keyVersion int
+	// TTLs are changed on config reload
+	bannerTTL   atomic.Int64
+	notFoundTTL atomic.Int64
 }
 
-func New(ctx context.Context, addr, user, password string, db, protocol int) (*Storage, error) {
+type Options struct {
+	// Mode is one of standalone, sentinel or cluster
+	Mode string
+	// Addrs are Redis address for standalone mode, sentinel addresses
+	// for sentinel mode and seed nodes for cluster mode
+	Addrs      []string
+	MasterName string
+
+	User             string
+	Password         string
+	SentinelUser     string
+	SentinelPassword string
+	DB               int
+	Protocol         int
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	TLS TLSOptions
+
+	KeyPrefix   string
+	BannerTTL   time.Duration
+	NotFoundTTL time.Duration
+	KeyVersion  int
+
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// New never fails because of unavailable Redis: the storage starts
+// in degraded mode and recovers once Redis answers again
+func New(ctx context.Context, opts Options) (*Storage, error) {
 	const op = "storage.redis.New"
 
-	url := fmt.Sprintf("redis://%v:%v@%v/%v?protocol=%v", user, password, addr, db, protocol)
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("%s: no Redis address", op)
+	}
 
-	opts, err := redis.ParseURL(url)
+	tlsConfig, err := opts.TLS.config()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	dbm := redis.NewClient(opts)
-	if status := dbm.Ping(ctx); status.Err() != nil {
-		return nil, status.Err()
+	universal := &redis.UniversalOptions{
+		Addrs:            opts.Addrs,
+		MasterName:       opts.MasterName,
+		DB:               opts.DB,
+		Protocol:         opts.Protocol,
+		Username:         opts.User,
+		Password:         opts.Password,
+		SentinelUsername: opts.SentinelUser,
+		SentinelPassword: opts.SentinelPassword,
+		PoolSize:         opts.PoolSize,
+		MinIdleConns:     opts.MinIdleConns,
+		DialTimeout:      opts.DialTimeout,
+		ReadTimeout:      opts.ReadTimeout,
+		WriteTimeout:     opts.WriteTimeout,
+		TLSConfig:        tlsConfig,
+	}
+
+	var db redis.UniversalClient
+	switch opts.Mode {
+	case ModeStandalone, "":
+		db = redis.NewClient(universal.Simple())
+	case ModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("%s: sentinel mode requires master name", op)
+		}
+		db = redis.NewFailoverClient(universal.Failover())
+	case ModeCluster:
+		db = redis.NewClusterClient(universal.Cluster())
+	default:
+		return nil, fmt.Errorf("%s: unknown mode %q", op, opts.Mode)
 	}
 
-	return &Storage{db: dbm}, nil
+	s := &Storage{
+		db:         db,
+		breaker:    newBreaker(opts.FailureThreshold, opts.OpenTimeout),
+		keyPrefix:  opts.KeyPrefix,
+		keyVersion: opts.KeyVersion,
+	}
+	s.SetTTL(opts.BannerTTL, opts.NotFoundTTL)
+
+	if err := s.db.Ping(ctx).Err(); err != nil {
+		s.breaker.forceOpen()
+	}
+
+	return s, nil
 }
 
-func (s *Storage) SetBanner(ctx context.Context, tagID, featureID int64, content []byte) error {
+// SetTTL changes TTL of banners and of "not found" results cached from now on
+func (s *Storage) SetTTL(bannerTTL, notFoundTTL time.Duration) {
+	s.bannerTTL.Store(int64(bannerTTL))
+	s.notFoundTTL.Store(int64(notFoundTTL))
+}
+
+func (s *Storage) BannerTTL() time.Duration {
+	return time.Duration(s.bannerTTL.Load())
+}
+
+func (s *Storage) NotFoundTTL() time.Duration {
+	return time.Duration(s.notFoundTTL.Load())
+}
+
+// Degraded reports whether Redis is skipped because of an outage
+func (s *Storage) Degraded() bool {
+	return s.breaker.open()
+}
+
+// Probe pings Redis every interval while it is degraded, so recovery
+// is noticed even without incoming traffic
+func (s *Storage) Probe(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.Degraded() || !s.breaker.allow() {
+				continue
+			}
+			s.breaker.done(s.db.Ping(ctx).Err())
+		}
+	}
+}
+
+// call runs fn unless the breaker is open and records its result;
+// redis.Nil is a regular miss, not a failure
+func (s *Storage) call(fn func() error) error {
+	if !s.breaker.allow() {
+		return storage.ErrCacheUnavailable
+	}
+
+	err := fn()
+	if errors.Is(err, redis.Nil) {
+		s.breaker.done(nil)
+	} else {
+		s.breaker.done(err)
+	}
+
+	return err
+}
+
+// bannerKey returns versioned key, so payloads of old schema are never read;
+// tag is a hash tag, so banners of one tag share cluster slot for MGET
+func (s *Storage) bannerKey(tagID, featureID int64) string {
+	return fmt.Sprintf("%sbanner:v%d:{%d}:%d", s.keyPrefix, s.keyVersion, tagID, featureID)
+}
+
+// SetBanner caches banner resolved for tagID, banner.TagID may be its ancestor
+func (s *Storage) SetBanner(ctx context.Context, tagID, featureID int64, banner *storage.ResolvedBanner) error {
 	const op = "storage.redis.SetBanner"
 
-	key := fmt.Sprintf("%d:%d", tagID, featureID)
-	err := s.db.Set(ctx, key, content, 5*time.Minute).Err()
+	value, err := json.Marshal(banner)
 	if err != nil {
 		return fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
 	}
 
+	err = s.call(func() error {
+		return s.db.Set(ctx, s.bannerKey(tagID, featureID), value, s.BannerTTL()).Err()
+	})
+	if errors.Is(err, storage.ErrCacheUnavailable) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SetBannerNotFound(ctx context.Context, tagID, featureID int64) error {
+	const op = "storage.redis.SetBannerNotFound"
+
+	err := s.call(func() error {
+		return s.db.Set(ctx, s.bannerKey(tagID, featureID), notFoundValue, s.NotFoundTTL()).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) GetBanner(ctx context.Context, key string) ([]byte, error) {
+func (s *Storage) GetBanner(ctx context.Context, tagID, featureID int64) (*storage.ResolvedBanner, error) {
 	const op = "storage.redis.GetBanner"
 
-	value, err := s.db.Get(ctx, key).Result()
+	var value string
+	err := s.call(func() (err error) {
+		value, err = s.db.Get(ctx, s.bannerKey(tagID, featureID)).Result()
+		return err
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrCacheMiss)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrCacheMiss, err)
+	}
+
+	// banner is known to be absent
+	if value == notFoundValue {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBannerNotFound)
+	}
+
+	var banner storage.ResolvedBanner
+	if err := json.Unmarshal([]byte(value), &banner); err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrCacheMiss, err)
+	}
+
+	return &banner, nil
+}
+
+// GetTagBanners reads banners of several features for tag with MGET, cache
+// misses are absent in result, nil value means banner is known to be absent
+func (s *Storage) GetTagBanners(ctx context.Context, tagID int64, featureIDs []int64) (map[int64]*storage.ResolvedBanner, error) {
+	const op = "storage.redis.GetTagBanners"
+
+	keys := make([]string, len(featureIDs))
+	for i, featureID := range featureIDs {
+		keys[i] = s.bannerKey(tagID, featureID)
+	}
+
+	var values []interface{}
+	err := s.call(func() (err error) {
+		values, err = s.db.MGet(ctx, keys...).Result()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrCacheMiss, err)
+	}
+
+	banners := make(map[int64]*storage.ResolvedBanner, len(featureIDs))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if str == notFoundValue {
+			banners[featureIDs[i]] = nil
+			continue
+		}
+
+		var banner storage.ResolvedBanner
+		if err := json.Unmarshal([]byte(str), &banner); err != nil {
+			continue
+		}
+		banners[featureIDs[i]] = &banner
+	}
+
+	return banners, nil
+}
+
+// SetTagBanners caches banners of several features for tag in a single
+// pipeline, nil value is cached as absent banner
+func (s *Storage) SetTagBanners(ctx context.Context, tagID int64, banners map[int64]*storage.ResolvedBanner) error {
+	const op = "storage.redis.SetTagBanners"
+
+	values := make(map[int64][]byte, len(banners))
+	for featureID, banner := range banners {
+		if banner == nil {
+			continue
+		}
+		value, err := json.Marshal(banner)
+		if err != nil {
+			return fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+		}
+		values[featureID] = value
+	}
+
+	err := s.call(func() error {
+		_, err := s.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for featureID, banner := range banners {
+				if banner == nil {
+					pipe.Set(ctx, s.bannerKey(tagID, featureID), notFoundValue, s.NotFoundTTL())
+					continue
+				}
+				pipe.Set(ctx, s.bannerKey(tagID, featureID), values[featureID], s.BannerTTL())
+			}
+			return nil
+		})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w: %w", op, storage.ErrBannerNotFound, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetBanners stores entries in a single pipeline
+func (s *Storage) SetBanners(ctx context.Context, entries []storage.CacheEntry) error {
+	const op = "storage.redis.SetBanners"
+
+	values := make([][]byte, len(entries))
+	for i, entry := range entries {
+		value, err := json.Marshal(storage.ResolvedBanner{TagID: entry.TagID, Content: entry.Content, Version: entry.Version, UpdatedAt: entry.UpdatedAt})
+		if err != nil {
+			return fmt.Errorf("%s: %w: %w", op, storage.ErrBannerInvalidData, err)
+		}
+		values[i] = value
+	}
+
+	err := s.call(func() error {
+		_, err := s.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, entry := range entries {
+				pipe.Set(ctx, s.bannerKey(entry.TagID, entry.FeatureID), values[i], s.BannerTTL())
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// FlushBanners removes cached banners of current key version,
+// zero tagID or featureID matches any value
+func (s *Storage) FlushBanners(ctx context.Context, tagID, featureID int64) (int64, error) {
+	const op = "storage.redis.FlushBanners"
+
+	var deleted atomic.Int64
+	err := s.call(func() error {
+		if tagID != 0 && featureID != 0 {
+			n, err := s.db.Del(ctx, s.bannerKey(tagID, featureID)).Result()
+			deleted.Add(n)
+			return err
+		}
+
+		tag, feature := "*", "*"
+		if tagID != 0 {
+			tag = fmt.Sprint(tagID)
+		}
+		if featureID != 0 {
+			feature = fmt.Sprint(featureID)
+		}
+		pattern := fmt.Sprintf("%sbanner:v%d:{%s}:%s", s.keyPrefix, s.keyVersion, tag, feature)
+
+		// SCAN is node local, so cluster is scanned master by master
+		if cluster, ok := s.db.(*redis.ClusterClient); ok {
+			return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+				n, err := scanDelete(ctx, client, pattern)
+				deleted.Add(n)
+				return err
+			})
+		}
+
+		n, err := scanDelete(ctx, s.db, pattern)
+		deleted.Add(n)
+		return err
+	})
+	if err != nil {
+		return deleted.Load(), fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted.Load(), nil
+}
+
+// scanDelete removes keys matching pattern in batches, keys are deleted
+// one by one in a pipeline to avoid cross slot errors in cluster
+func scanDelete(ctx context.Context, client redis.UniversalClient, pattern string) (int64, error) {
+	const batchSize = 1000
+
+	var deleted int64
+	keys := make([]string, 0, batchSize)
+
+	flush := func() error {
+		cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			deleted += cmd.(*redis.IntCmd).Val()
+		}
+		keys = keys[:0]
+		return nil
+	}
+
+	iter := client.Scan(ctx, 0, pattern, batchSize).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < batchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return deleted, err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if len(keys) > 0 {
+		if err := flush(); err != nil {
+			return deleted, err
+		}
 	}
 
-	return []byte(value), nil
+	return deleted, nil
 }
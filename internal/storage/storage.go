@@ -1,6 +1,10 @@
 package storage
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 var (
 	ErrBannerNotFound    = errors.New("banner not found")
@@ -8,4 +12,48 @@ var (
 	ErrBannerExists      = errors.New("banner exists")
 	ErrBannerNotExists   = errors.New("banner not exists")
 	ErrBannerNotAdd      = errors.New("cannot be added")
+	ErrBannerVersion     = errors.New("banner version mismatch")
+	ErrCacheMiss         = errors.New("cache miss")
+	ErrCacheUnavailable  = errors.New("cache unavailable")
+
+	ErrFeatureNotFound         = errors.New("feature not found")
+	ErrFeatureExists           = errors.New("feature exists")
+	ErrFeatureInUse            = errors.New("feature is used by banners")
+	ErrFeatureHasActiveBanners = errors.New("feature has active banners")
+
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTagExists           = errors.New("tag exists")
+	ErrTagInUse            = errors.New("tag is used by banners")
+	ErrTagHasActiveBanners = errors.New("tag has active banners")
+	ErrTagInvalidParent    = errors.New("tag has invalid parent")
+
+	ErrIdempotencyMismatch   = errors.New("idempotency key is used with another request")
+	ErrIdempotencyInProgress = errors.New("request with idempotency key is in progress")
 )
+
+// CacheEntry is a banner content for a single tag:feature pair
+type CacheEntry struct {
+	TagID     int64
+	FeatureID int64
+	Content   []byte
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// ResolvedBanner is a banner content found for tag:feature pair,
+// TagID is the tag it matched, the requested one or its ancestor,
+// Fallback is set when default banner of the feature is served instead,
+// Version and UpdatedAt of the banner make conditional requests possible
+type ResolvedBanner struct {
+	TagID     int64           `json:"tag_id"`
+	Content   json.RawMessage `json:"content"`
+	Fallback  bool            `json:"fallback"`
+	Version   int64           `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// IdempotentResponse is a response stored for Idempotency-Key to be replayed
+type IdempotentResponse struct {
+	Status int
+	Body   []byte
+}
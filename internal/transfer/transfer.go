@@ -0,0 +1,339 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatYAML  = "yaml"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// csvHeader is column order of CSV, tag_ids are separated by ";" and content is JSON
+var csvHeader = []string{"banner_id", "feature_id", "tag_ids", "is_active", "content", "created_at", "updated_at", "version"}
+
+// Record is a banner as it is exported and imported
+type Record struct {
+	BannerID  int64           `json:"banner_id"`
+	FeatureID int64           `json:"feature_id"`
+	TagIDs    []int64         `json:"tag_ids"`
+	IsActive  bool            `json:"is_active"`
+	Content   json.RawMessage `json:"content"`
+	CreatedAt string          `json:"created_at,omitempty"`
+	UpdatedAt string          `json:"updated_at,omitempty"`
+	Version   int64           `json:"version,omitempty"`
+}
+
+// yamlRecord holds content as a value, so it is written as YAML rather than JSON string
+type yamlRecord struct {
+	BannerID  int64       `yaml:"banner_id"`
+	FeatureID int64       `yaml:"feature_id"`
+	TagIDs    []int64     `yaml:"tag_ids,flow"`
+	IsActive  bool        `yaml:"is_active"`
+	Content   interface{} `yaml:"content"`
+	CreatedAt string      `yaml:"created_at,omitempty"`
+	UpdatedAt string      `yaml:"updated_at,omitempty"`
+	Version   int64       `yaml:"version,omitempty"`
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatYAML:
+		return "application/yaml"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func Valid(format string) bool {
+	return format == FormatJSONL || format == FormatCSV || format == FormatYAML
+}
+
+// Writer writes records one by one, Close flushes buffered output
+type Writer struct {
+	format string
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	yaml   *yaml.Encoder
+	header bool
+	// written is set by the first record, YAML encoder can't be closed before it
+	written bool
+}
+
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	if !Valid(format) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	buf := bufio.NewWriter(w)
+	writer := &Writer{format: format, buf: buf}
+	switch format {
+	case FormatCSV:
+		writer.csv = csv.NewWriter(buf)
+	case FormatYAML:
+		writer.yaml = yaml.NewEncoder(buf)
+		writer.yaml.SetIndent(2)
+	}
+
+	return writer, nil
+}
+
+func (w *Writer) Write(rec Record) error {
+	switch w.format {
+	case FormatCSV:
+		if !w.header {
+			if err := w.csv.Write(csvHeader); err != nil {
+				return err
+			}
+			w.header = true
+		}
+		tagIDs := make([]string, len(rec.TagIDs))
+		for i, tagID := range rec.TagIDs {
+			tagIDs[i] = strconv.FormatInt(tagID, 10)
+		}
+		return w.csv.Write([]string{
+			strconv.FormatInt(rec.BannerID, 10),
+			strconv.FormatInt(rec.FeatureID, 10),
+			strings.Join(tagIDs, ";"),
+			strconv.FormatBool(rec.IsActive),
+			string(rec.Content),
+			rec.CreatedAt,
+			rec.UpdatedAt,
+			strconv.FormatInt(rec.Version, 10),
+		})
+	case FormatYAML:
+		var content interface{}
+		if err := json.Unmarshal(rec.Content, &content); err != nil {
+			return err
+		}
+		w.written = true
+		return w.yaml.Encode(yamlRecord{
+			BannerID:  rec.BannerID,
+			FeatureID: rec.FeatureID,
+			TagIDs:    rec.TagIDs,
+			IsActive:  rec.IsActive,
+			Content:   content,
+			CreatedAt: rec.CreatedAt,
+			UpdatedAt: rec.UpdatedAt,
+			Version:   rec.Version,
+		})
+	default:
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if _, err := w.buf.Write(data); err != nil {
+			return err
+		}
+		return w.buf.WriteByte('\n')
+	}
+}
+
+// Flush writes buffered records, it is called periodically while streaming
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	return w.buf.Flush()
+}
+
+func (w *Writer) Close() error {
+	if w.format == FormatCSV && !w.header {
+		if err := w.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	if w.yaml != nil && w.written {
+		if err := w.yaml.Close(); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// Reader reads records one by one, Read returns io.EOF after the last one
+type Reader struct {
+	format string
+	line   int
+	lines  *bufio.Scanner
+	csv    *csv.Reader
+	yaml   *yaml.Decoder
+	header map[string]int
+}
+
+func NewReader(r io.Reader, format string) (*Reader, error) {
+	if !Valid(format) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	reader := &Reader{format: format}
+	switch format {
+	case FormatCSV:
+		reader.csv = csv.NewReader(r)
+		reader.csv.FieldsPerRecord = -1
+	case FormatYAML:
+		reader.yaml = yaml.NewDecoder(r)
+	default:
+		reader.lines = bufio.NewScanner(r)
+		reader.lines.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	}
+
+	return reader, nil
+}
+
+// Line is number of the last record read: line of JSONL, row of CSV or document of YAML
+func (r *Reader) Line() int {
+	return r.line
+}
+
+func (r *Reader) Read() (Record, error) {
+	switch r.format {
+	case FormatCSV:
+		return r.readCSV()
+	case FormatYAML:
+		return r.readYAML()
+	default:
+		return r.readJSONL()
+	}
+}
+
+func (r *Reader) readJSONL() (Record, error) {
+	for r.lines.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.lines.Text())
+		if line == "" {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return rec, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		return Record{}, err
+	}
+
+	return Record{}, io.EOF
+}
+
+func (r *Reader) readCSV() (Record, error) {
+	if r.header == nil {
+		header, err := r.csv.Read()
+		if err != nil {
+			return Record{}, err
+		}
+		r.line++
+		r.header = make(map[string]int, len(header))
+		for i, name := range header {
+			r.header[strings.TrimSpace(name)] = i
+		}
+		for _, name := range []string{"feature_id", "tag_ids", "content"} {
+			if _, ok := r.header[name]; !ok {
+				return Record{}, fmt.Errorf("line 1: column %s is required", name)
+			}
+		}
+	}
+
+	row, err := r.csv.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	r.line++
+
+	field := func(name string) string {
+		i, ok := r.header[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	fail := func(name string) (Record, error) {
+		return Record{}, fmt.Errorf("line %d: invalid %s", r.line, name)
+	}
+
+	var rec Record
+	if v := field("banner_id"); v != "" {
+		if rec.BannerID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fail("banner_id")
+		}
+	}
+	if rec.FeatureID, err = strconv.ParseInt(field("feature_id"), 10, 64); err != nil {
+		return fail("feature_id")
+	}
+	for _, v := range strings.Split(field("tag_ids"), ";") {
+		if v == "" {
+			continue
+		}
+		tagID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fail("tag_ids")
+		}
+		rec.TagIDs = append(rec.TagIDs, tagID)
+	}
+	if v := field("is_active"); v != "" {
+		if rec.IsActive, err = strconv.ParseBool(v); err != nil {
+			return fail("is_active")
+		}
+	}
+	rec.Content = json.RawMessage(field("content"))
+	if !json.Valid(rec.Content) {
+		return fail("content")
+	}
+	rec.CreatedAt = field("created_at")
+	rec.UpdatedAt = field("updated_at")
+	if v := field("version"); v != "" {
+		if rec.Version, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fail("version")
+		}
+	}
+
+	return rec, nil
+}
+
+func (r *Reader) readYAML() (Record, error) {
+	var rec yamlRecord
+	if err := r.yaml.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Record{}, io.EOF
+		}
+		return Record{}, fmt.Errorf("document %d: %w", r.line+1, err)
+	}
+	r.line++
+
+	content, err := json.Marshal(rec.Content)
+	if err != nil {
+		return Record{}, fmt.Errorf("document %d: content: %w", r.line, err)
+	}
+
+	return Record{
+		BannerID:  rec.BannerID,
+		FeatureID: rec.FeatureID,
+		TagIDs:    rec.TagIDs,
+		IsActive:  rec.IsActive,
+		Content:   content,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+		Version:   rec.Version,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var records = []Record{
+	{
+		BannerID:  1,
+		FeatureID: 2,
+		TagIDs:    []int64{3, 4},
+		IsActive:  true,
+		Content:   json.RawMessage(`{"title": "a, \"quoted\" title", "text": "line\nbreak", "url": "https://example.com"}`),
+		CreatedAt: "2024-04-01T10:00:00Z",
+		UpdatedAt: "2024-04-02T10:00:00Z",
+		Version:   3,
+	},
+	{
+		BannerID:  5,
+		FeatureID: 6,
+		TagIDs:    []int64{7},
+		Content:   json.RawMessage(`{"items": [1, 2.5, {"nested": null}], "flag": false}`),
+		CreatedAt: "2024-04-03T10:00:00Z",
+		UpdatedAt: "2024-04-03T10:00:00Z",
+		Version:   1,
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatCSV, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, rec := range records {
+				if err := w.Write(rec); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			got := readAll(t, &buf, format)
+			if len(got) != len(records) {
+				t.Fatalf("read %d records, want %d", len(got), len(records))
+			}
+			for i := range records {
+				assertRecord(t, got[i], records[i])
+			}
+		})
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, format := range []string{FormatJSONL, FormatCSV, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if got := readAll(t, &buf, format); len(got) != 0 {
+				t.Errorf("read %d records, want none", len(got))
+			}
+		})
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	input := `{"feature_id": 1, "tag_ids": [1], "content": {}}
+
+{"feature_id": 2, "tag_ids": [2], "content": {}}
+not json
+`
+	r, err := NewReader(strings.NewReader(input), FormatJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []int{1, 3} {
+		if _, err := r.Read(); err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if r.Line() != want {
+			t.Errorf("Line = %d, want %d", r.Line(), want)
+		}
+	}
+	if _, err := r.Read(); err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Errorf("Read error = %v, want error of line 4", err)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "missing column", input: "feature_id,content\n1,{}\n", err: "line 1: column tag_ids is required"},
+		{name: "invalid content", input: "feature_id,tag_ids,content\n1,2,{\n", err: "line 2: invalid content"},
+		{name: "invalid tag", input: "feature_id,tag_ids,content\n1,2;x,{}\n", err: "line 2: invalid tag_ids"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.input), FormatCSV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Read(); err == nil || err.Error() != tt.err {
+				t.Errorf("Read error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewWriter error = %v, want ErrUnknownFormat", err)
+	}
+	if _, err := NewReader(strings.NewReader(""), "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewReader error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func readAll(t *testing.T, r io.Reader, format string) []Record {
+	t.Helper()
+
+	reader, err := NewReader(r, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var recs []Record
+	for {
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return recs
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// assertRecord compares content as JSON values, formats don't keep its key order and spacing
+func assertRecord(t *testing.T, got, want Record) {
+	t.Helper()
+
+	var gotContent, wantContent interface{}
+	if err := json.Unmarshal(got.Content, &gotContent); err != nil {
+		t.Fatalf("banner %d: content: %v", got.BannerID, err)
+	}
+	if err := json.Unmarshal(want.Content, &wantContent); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotContent, wantContent) {
+		t.Errorf("banner %d: content = %s, want %s", want.BannerID, got.Content, want.Content)
+	}
+
+	got.Content, want.Content = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}